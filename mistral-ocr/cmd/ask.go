@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/chunk"
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+	"github.com/spf13/cobra"
+)
+
+var (
+	chatModel  string
+	noStream   bool
+	maxContext int
+
+	askCmd = &cobra.Command{
+		Use:   "ask [file_or_url] [question]",
+		Short: "Ask a question about a document",
+		Long: `Answer a question about a document with a Mistral chat model. The document is OCR'd
+first (cached results are reused) and its pages are sent with the question; the answer
+cites the pages it is based on as [p. N].
+
+An OCR JSON file written by the process command can be given instead of a document.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			askDocument(cmd.Context(), args[0], args[1])
+		},
+	}
+)
+
+func init() {
+	askCmd.Flags().StringVar(&chatModel, "chat-model", mistral.DefaultChatModel, "Chat model used to answer")
+	askCmd.Flags().BoolVar(&noStream, "no-stream", false, "Print the answer once it is complete instead of streaming it")
+	askCmd.Flags().StringVar(&pageSpec, "pages", "", "Only use these pages, e.g. 1-5,9,12-")
+	askCmd.Flags().IntVar(&maxContext, "max-context", 100000, "Maximum estimated tokens of document text sent with the question (0 for unlimited)")
+}
+
+const askSystemPrompt = `You answer questions about a document using only the OCR text provided by the user.
+Each page is enclosed in <page number="N"> tags. Support every statement with the pages it
+comes from, written as [p. N] or [p. N, M]. If the document does not contain the answer, say so.`
+
+// citationPattern matches the [p. N] citations the model is asked to write
+var citationPattern = regexp.MustCompile(`\[pp?\.\s*([0-9,\s–-]+)\]`)
+
+// askPrompt formats the pages of result and the question as the user message.
+// It fails if the pages are estimated at more than maxTokens tokens, unless
+// maxTokens is 0.
+func askPrompt(result *mistral.OCRResult, question string, maxTokens int) (string, error) {
+	var b strings.Builder
+	for _, page := range result.Pages {
+		fmt.Fprintf(&b, "<page number=\"%d\">\n%s\n</page>\n\n", page.Index+1, strings.TrimSpace(page.Markdown))
+	}
+	if tokens := chunk.EstimateTokens(b.String()); maxTokens > 0 && tokens > maxTokens {
+		return "", fmt.Errorf("the %d pages are about %d tokens, more than --max-context %d; select fewer pages with --pages or raise --max-context",
+			len(result.Pages), tokens, maxTokens)
+	}
+	b.WriteString("Question: ")
+	b.WriteString(question)
+	return b.String(), nil
+}
+
+// citedPages returns the sorted pages of result cited in answer
+func citedPages(answer string, result *mistral.OCRResult) []int {
+	exists := make(map[int]bool)
+	for _, page := range result.Pages {
+		exists[page.Index+1] = true
+	}
+
+	seen := make(map[int]bool)
+	var pages []int
+	add := func(n int) {
+		if exists[n] && !seen[n] {
+			seen[n] = true
+			pages = append(pages, n)
+		}
+	}
+
+	for _, m := range citationPattern.FindAllStringSubmatch(answer, -1) {
+		for _, part := range strings.Split(m[1], ",") {
+			from, to, isRange := strings.Cut(strings.ReplaceAll(strings.TrimSpace(part), "–", "-"), "-")
+			start, err := strconv.Atoi(strings.TrimSpace(from))
+			if err != nil {
+				continue
+			}
+			end := start
+			if isRange {
+				if end, err = strconv.Atoi(strings.TrimSpace(to)); err != nil || end < start {
+					end = start
+				}
+			}
+			for n := start; n <= end && n-start < 1000; n++ {
+				add(n)
+			}
+		}
+	}
+	sort.Ints(pages)
+	return pages
+}
+
+func askDocument(ctx context.Context, fileOrURL, question string) {
+	// Progress goes to stderr so the answer can be piped from stdout
+	logf := func(format string, a ...interface{}) {
+		fmt.Fprintf(os.Stderr, format, a...)
+	}
+
+	client := newClient()
+
+	var result *mistral.OCRResult
+	if !isURL(fileOrURL) && strings.EqualFold(filepath.Ext(fileOrURL), ".json") {
+		var err error
+		if result, err = readOCRResult(fileOrURL); err != nil {
+			fmt.Printf("Error reading JSON file: %v\n", err)
+			os.Exit(1)
+		}
+		sel, err := parsePageSelection(pageSpec)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		filterPages(result, sel)
+	} else {
+		respData, err := ocrDocument(ctx, mistralProvider{client: client}, fileOrURL, logf)
+		if err != nil {
+			exitIfInterrupted(ctx)
+			fmt.Printf("Error processing document: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+		if result, err = mistral.ParseOCRResult(respData); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if len(result.Pages) == 0 {
+		fmt.Println("Error: the document has no pages")
+		os.Exit(1)
+	}
+
+	prompt, err := askPrompt(result, question, maxContext)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	req := &mistral.ChatRequest{
+		Model: chatModel,
+		Messages: []mistral.ChatMessage{
+			{Role: "system", Content: askSystemPrompt},
+			{Role: "user", Content: prompt},
+		},
+	}
+
+	logf("Asking %s about %d pages...\n\n", chatModel, len(result.Pages))
+
+	var answer string
+	if noStream {
+		resp, err := client.Chat(ctx, req)
+		if err != nil {
+			exitIfInterrupted(ctx)
+			fmt.Printf("Error asking question: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+		answer = resp.Choices[0].Message.Content
+		fmt.Print(answer)
+	} else {
+		resp, err := client.ChatStream(ctx, req, func(delta string) {
+			fmt.Print(delta)
+		})
+		if err != nil {
+			exitIfInterrupted(ctx)
+			fmt.Printf("\nError asking question: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+		answer = resp.Choices[0].Message.Content
+	}
+	fmt.Println()
+
+	if pages := citedPages(answer, result); len(pages) > 0 {
+		names := make([]string, len(pages))
+		for i, n := range pages {
+			names[i] = strconv.Itoa(n)
+		}
+		label := "page"
+		if len(pages) > 1 {
+			label = "pages"
+		}
+		fmt.Printf("\nSources: %s %s\n", label, strings.Join(names, ", "))
+	}
+}
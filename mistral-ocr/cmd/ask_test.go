@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+)
+
+func TestAskPromptMaxContext(t *testing.T) {
+	result := &mistral.OCRResult{Pages: []mistral.Page{
+		{Index: 0, Markdown: strings.Repeat("word ", 200)},
+		{Index: 1, Markdown: strings.Repeat("word ", 200)},
+	}}
+
+	tests := []struct {
+		name      string
+		maxTokens int
+		wantErr   bool
+	}{
+		{name: "unlimited", maxTokens: 0},
+		{name: "fits", maxTokens: 1000},
+		{name: "too long", maxTokens: 100, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompt, err := askPrompt(result, "Why?", tt.maxTokens)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "--max-context") {
+					t.Errorf("error = %v, want the context limit reported", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(prompt, `<page number="2">`) || !strings.HasSuffix(prompt, "Question: Why?") {
+				t.Errorf("unexpected prompt %q", prompt)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/tesseract"
+	"github.com/spf13/cobra"
+)
+
+var (
+	batchOutputDir string
+	batchWorkers   int
+	batchFormat    string
+
+	batchCmd = &cobra.Command{
+		Use:   "batch [dir_or_glob...]",
+		Short: "Process many documents concurrently",
+		Long: `Process every supported document (PDF, image) found in the given directories,
+glob patterns or files. Directories are walked recursively and the input tree is
+mirrored into the output directory, e.g. scans/2024/a.pdf becomes <output-dir>/2024/a.md.`,
+		Args: cobra.MinimumNArgs(1),
+		PreRun: func(cmd *cobra.Command, args []string) {
+			if includeImages {
+				includeImageBase64 = true
+			}
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			runBatch(cmd.Context(), args)
+		},
+	}
+)
+
+func init() {
+	batchCmd.Flags().StringVarP(&batchOutputDir, "output-dir", "d", "batch_output", "Directory to mirror the input tree into")
+	batchCmd.Flags().IntVarP(&batchWorkers, "workers", "w", 4, "Number of documents processed concurrently")
+	batchCmd.Flags().StringVar(&batchFormat, "format", "markdown", "Output format: markdown, json or both")
+	batchCmd.Flags().BoolVar(&includeImages, "images", false, "Include extracted images in markdown (if available)")
+	batchCmd.Flags().BoolVar(&includePageBreaks, "page-breaks", true, "Include page break indicators between pages")
+	batchCmd.Flags().BoolVar(&titleFromFilename, "title-from-filename", true, "Use filename as document title")
+	batchCmd.Flags().BoolVar(&keepUpload, "keep-upload", false, "Keep the uploaded files in your Mistral account instead of deleting them after OCR")
+	batchCmd.Flags().StringVar(&providerName, "provider", "", "OCR provider: mistral or tesseract (defaults to MISTRAL_OCR_PROVIDER env variable or mistral)")
+	batchCmd.Flags().StringVar(&tesseractLang, "tesseract-lang", "", "Tesseract languages, e.g. eng+deu (defaults to MISTRAL_OCR_TESSERACT_LANG env variable)")
+	batchCmd.Flags().IntVar(&tesseractDPI, "tesseract-dpi", tesseract.DefaultDPI, "Resolution PDF pages are rasterized at for tesseract")
+}
+
+// batchSupportedExtensions lists the file types picked up when walking directories
+var batchSupportedExtensions = map[string]bool{
+	".pdf":  true,
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".webp": true,
+	".gif":  true,
+}
+
+// batchJob is a single document found in the batch inputs
+type batchJob struct {
+	// Path is the document on disk
+	Path string
+	// RelPath is the document path relative to the input it was found in
+	RelPath string
+	// Output is the output path relative to the output directory, without extension
+	Output string
+}
+
+// batchSummary collects the outcome of a batch run
+type batchSummary struct {
+	mu        sync.Mutex
+	succeeded int
+	pages     int
+	failures  map[string]error
+}
+
+func (s *batchSummary) success(pages int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.succeeded++
+	s.pages += pages
+}
+
+func (s *batchSummary) failure(path string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[path] = err
+}
+
+func runBatch(ctx context.Context, inputs []string) {
+	switch batchFormat {
+	case "markdown", "json", "both":
+	default:
+		fmt.Printf("Error: unsupported format '%s' (expected markdown, json or both)\n", batchFormat)
+		os.Exit(1)
+	}
+	if batchWorkers < 1 {
+		fmt.Println("Error: --workers must be at least 1")
+		os.Exit(1)
+	}
+
+	jobs, err := collectBatchJobs(inputs)
+	if err != nil {
+		fmt.Printf("Error collecting input files: %v\n", err)
+		os.Exit(1)
+	}
+	if len(jobs) == 0 {
+		fmt.Println("Error: no supported documents found")
+		os.Exit(1)
+	}
+
+	provider := newProvider()
+	fmt.Printf("Processing %d documents with %d workers\n", len(jobs), batchWorkers)
+
+	summary := &batchSummary{failures: make(map[string]error)}
+	queue := make(chan batchJob)
+
+	var wg sync.WaitGroup
+	for i := 0; i < batchWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				pages, err := processBatchJob(ctx, provider, job)
+				if err != nil {
+					fmt.Printf("FAILED %s: %v\n", job.Path, err)
+					summary.failure(job.Path, err)
+					continue
+				}
+				fmt.Printf("OK     %s (%d pages)\n", job.Path, pages)
+				summary.success(pages)
+			}
+		}()
+	}
+
+enqueue:
+	for _, job := range jobs {
+		select {
+		case queue <- job:
+		case <-ctx.Done():
+			break enqueue
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	printBatchSummary(summary, len(jobs))
+
+	// Documents that finished keep their outputs, half written ones are removed
+	exitIfInterrupted(ctx)
+	if len(summary.failures) > 0 {
+		os.Exit(1)
+	}
+}
+
+// processBatchJob runs OCR for one document and writes its outputs, returning the page count
+func processBatchJob(ctx context.Context, provider OCRProvider, job batchJob) (int, error) {
+	respData, err := ocrDocument(ctx, provider, job.Path, discardf)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := mistral.ParseOCRResult(respData)
+	if err != nil {
+		return 0, err
+	}
+
+	base := filepath.Join(batchOutputDir, job.Output)
+	if err := os.MkdirAll(filepath.Dir(base), 0755); err != nil {
+		return 0, fmt.Errorf("error creating output directory: %v", err)
+	}
+
+	// Outputs of a document count as partial until all of them are written
+	var outputs []string
+	if batchFormat == "json" || batchFormat == "both" {
+		if err := writeOutputFile(base+".json", respData); err != nil {
+			return 0, fmt.Errorf("error writing JSON file: %v", err)
+		}
+		outputs = append(outputs, base+".json")
+	}
+	if batchFormat == "markdown" || batchFormat == "both" {
+		markdown, err := renderMarkdown(result, job.Path, batchOutputDir, filepath.Dir(base))
+		if err != nil {
+			return 0, err
+		}
+		if err := writeOutputFile(base+".md", []byte(markdown)); err != nil {
+			return 0, fmt.Errorf("error writing markdown file: %v", err)
+		}
+		outputs = append(outputs, base+".md")
+	}
+	partialOutputs.forget(outputs...)
+
+	return len(result.Pages), nil
+}
+
+// collectBatchJobs expands directories, glob patterns and files into a
+// deduplicated list of supported documents
+func collectBatchJobs(inputs []string) ([]batchJob, error) {
+	var jobs []batchJob
+	seen := make(map[string]bool)
+
+	add := func(path, root string) {
+		abs, err := filepath.Abs(path)
+		if err != nil || seen[abs] {
+			return
+		}
+		seen[abs] = true
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			rel = filepath.Base(path)
+		}
+		jobs = append(jobs, batchJob{Path: path, RelPath: rel})
+	}
+
+	for _, input := range inputs {
+		matches := []string{input}
+		root := filepath.Dir(input)
+		if strings.ContainsAny(input, "*?[") {
+			var err error
+			matches, err = filepath.Glob(input)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern '%s': %v", input, err)
+			}
+			root = globRoot(input)
+		}
+
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+
+			if !info.IsDir() {
+				add(match, root)
+				continue
+			}
+
+			// Directories given directly are mirrored from their own root
+			walkRoot := root
+			if match == input {
+				walkRoot = match
+			}
+			err = filepath.WalkDir(match, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.IsDir() && batchSupportedExtensions[strings.ToLower(filepath.Ext(path))] {
+					add(path, walkRoot)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Path < jobs[j].Path })
+	if err := assignBatchOutputs(jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// assignBatchOutputs names the outputs of jobs after their relative path
+// without extension. Documents that would share an output, such as a.pdf and
+// a.png in one directory, keep their extension instead (a.pdf.md, a.png.md).
+func assignBatchOutputs(jobs []batchJob) error {
+	stems := make(map[string]int)
+	for _, job := range jobs {
+		stems[outputKey(strings.TrimSuffix(job.RelPath, filepath.Ext(job.RelPath)))]++
+	}
+
+	owners := make(map[string]string)
+	for i, job := range jobs {
+		output := strings.TrimSuffix(job.RelPath, filepath.Ext(job.RelPath))
+		if stems[outputKey(output)] > 1 {
+			output = job.RelPath
+		}
+		// Same relative paths from different inputs cannot be told apart
+		if owner, ok := owners[outputKey(output)]; ok {
+			return fmt.Errorf("%s and %s would be written to the same outputs %s.*", owner, job.Path, filepath.Join(batchOutputDir, output))
+		}
+		owners[outputKey(output)] = job.Path
+		jobs[i].Output = output
+	}
+	return nil
+}
+
+// outputKey compares output paths case-insensitively, as the file system may
+func outputKey(path string) string {
+	return strings.ToLower(filepath.Clean(path))
+}
+
+// globRoot returns the directory part of pattern before its first wildcard
+func globRoot(pattern string) string {
+	dir := filepath.Dir(pattern)
+	for strings.ContainsAny(dir, "*?[") {
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
+
+func printBatchSummary(summary *batchSummary, total int) {
+	fmt.Println()
+	fmt.Println("Batch summary:")
+	fmt.Printf("  Documents:  %d\n", total)
+	fmt.Printf("  Succeeded:  %d\n", summary.succeeded)
+	fmt.Printf("  Failed:     %d\n", len(summary.failures))
+	fmt.Printf("  Skipped:    %d\n", total-summary.succeeded-len(summary.failures))
+	fmt.Printf("  Pages:      %d\n", summary.pages)
+
+	if len(summary.failures) > 0 {
+		var paths []string
+		for path := range summary.failures {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		fmt.Println("Failures:")
+		for _, path := range paths {
+			fmt.Printf("  %s: %v\n", path, summary.failures[path])
+		}
+	}
+}
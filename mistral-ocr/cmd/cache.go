@@ -0,0 +1,316 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cacheDir   string
+	noCache    bool
+	pruneOlder string
+	pruneAll   bool
+
+	cacheCmd = &cobra.Command{
+		Use:   "cache",
+		Short: "Inspect and prune the local OCR result cache",
+		Long: `OCR results of local files are cached by the SHA-256 of the file content and
+the OCR options, so processing the same document twice does not upload and bill it again.`,
+	}
+
+	cacheLsCmd = &cobra.Command{
+		Use:   "ls",
+		Short: "List cached OCR results",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			listCache()
+		},
+	}
+
+	cachePruneCmd = &cobra.Command{
+		Use:   "prune",
+		Short: "Remove cached OCR results",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			pruneCache()
+		},
+	}
+)
+
+func init() {
+	RootCmd.PersistentFlags().StringVar(&cacheDir, "cache-dir", "", "OCR result cache directory (defaults to MISTRAL_OCR_CACHE_DIR env variable or the user cache dir)")
+	RootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Do not read or write cached OCR results")
+
+	cachePruneCmd.Flags().StringVar(&pruneOlder, "older-than", "", "Remove entries older than this age, e.g. 72h or 30d")
+	cachePruneCmd.Flags().BoolVar(&pruneAll, "all", false, "Remove all entries")
+
+	cacheCmd.AddCommand(cacheLsCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
+}
+
+// cacheEntry is a cached OCR response together with what produced it
+type cacheEntry struct {
+	Key       string          `json:"key"`
+	Source    string          `json:"source"`
+	SHA256    string          `json:"sha256"`
+	Model     string          `json:"model"`
+	CreatedAt time.Time       `json:"created_at"`
+	Response  json.RawMessage `json:"response"`
+
+	// path and size describe the file the entry was read from
+	path string
+	size int64
+}
+
+// resolveCacheDir returns the cache directory from flag, environment or the user cache dir
+func resolveCacheDir() (string, error) {
+	if dir := flagOrEnv(cacheDir, "MISTRAL_OCR_CACHE_DIR"); dir != "" {
+		return dir, nil
+	}
+
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine cache directory: %v", err)
+	}
+	return filepath.Join(base, "mistral-ocr"), nil
+}
+
+// dataSHA256 returns the hex encoded SHA-256 of data
+func dataSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fileSHA256 returns the hex encoded SHA-256 of the file at path
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cacheKey derives the cache key from the document hash and every OCR option
+// except the document location, which changes with each upload
+func cacheKey(sum, model string, req mistral.OCRRequest) string {
+	req.Document = mistral.Document{}
+	req.Model = model
+	options, _ := json.Marshal(req)
+
+	h := sha256.New()
+	h.Write([]byte(sum))
+	h.Write(options)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// loadCachedResult returns the cached response for key, if any
+func loadCachedResult(key string) ([]byte, bool) {
+	dir, err := resolveCacheDir()
+	if err != nil {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, key+".json"))
+	if err != nil {
+		return nil, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || len(entry.Response) == 0 {
+		return nil, false
+	}
+	return entry.Response, true
+}
+
+// storeCachedResult saves resp under key. Failures only disable caching, so
+// they are returned for the caller to report rather than abort on.
+func storeCachedResult(key, source, sum, model string, resp []byte) error {
+	dir, err := resolveCacheDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	if source == stdinArg {
+		source = "stdin"
+	} else if abs, err := filepath.Abs(source); err == nil {
+		source = abs
+	}
+
+	data, err := json.Marshal(cacheEntry{
+		Key:       key,
+		Source:    source,
+		SHA256:    sum,
+		Model:     model,
+		CreatedAt: time.Now().UTC(),
+		Response:  json.RawMessage(resp),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so concurrent readers never see partial entries
+	tmp, err := os.CreateTemp(dir, ".entry-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	tmp.Close()
+	return os.Rename(tmp.Name(), filepath.Join(dir, key+".json"))
+}
+
+// readCacheEntries loads all cache entries along with the files they were read
+// from. Files that are not cache entries named after their key are skipped.
+func readCacheEntries() (string, []cacheEntry, error) {
+	dir, err := resolveCacheDir()
+	if err != nil {
+		return "", nil, err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return "", nil, err
+	}
+
+	var entries []cacheEntry
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		var entry cacheEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			continue
+		}
+		if !isCacheKey(entry.Key) || filepath.Base(path) != entry.Key+".json" {
+			continue
+		}
+		entry.path, entry.size = path, int64(len(data))
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].CreatedAt.After(entries[j].CreatedAt) })
+	return dir, entries, nil
+}
+
+// isCacheKey reports whether key has the form returned by cacheKey
+func isCacheKey(key string) bool {
+	if len(key) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(key)
+	return err == nil && strings.ToLower(key) == key
+}
+
+func listCache() {
+	dir, entries, err := readCacheEntries()
+	if err != nil {
+		fmt.Printf("Error reading cache: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(entries) == 0 {
+		fmt.Printf("Cache %s is empty\n", dir)
+		return
+	}
+
+	var total int64
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tCREATED\tMODEL\tSIZE\tSOURCE")
+	for _, entry := range entries {
+		total += entry.size
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			entry.Key[:12], entry.CreatedAt.Local().Format("2006-01-02 15:04"), entry.Model,
+			formatBytes(entry.size), entry.Source)
+	}
+	w.Flush()
+
+	fmt.Printf("%d entries, %s in %s\n", len(entries), formatBytes(total), dir)
+}
+
+func pruneCache() {
+	if pruneOlder == "" && !pruneAll {
+		fmt.Println("Error: specify --older-than or --all")
+		os.Exit(1)
+	}
+
+	var cutoff time.Time
+	if !pruneAll {
+		age, err := parseAge(pruneOlder)
+		if err != nil {
+			fmt.Printf("Error: invalid --older-than value: %v\n", err)
+			os.Exit(1)
+		}
+		cutoff = time.Now().Add(-age)
+	}
+
+	_, entries, err := readCacheEntries()
+	if err != nil {
+		fmt.Printf("Error reading cache: %v\n", err)
+		os.Exit(1)
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		if !pruneAll && entry.CreatedAt.After(cutoff) {
+			continue
+		}
+		if err := os.Remove(entry.path); err != nil {
+			fmt.Printf("Error removing cache entry %s: %v\n", entry.Key[:12], err)
+			continue
+		}
+		removed++
+	}
+
+	fmt.Printf("Removed %d of %d cache entries\n", removed, len(entries))
+}
+
+// parseAge parses a duration that may also use a "d" suffix for days
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number of days '%s'", days)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// formatBytes renders n as a human readable size
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(n)/1024/1024)
+	case n >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
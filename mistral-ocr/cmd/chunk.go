@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/chunk"
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+	"github.com/spf13/cobra"
+)
+
+var (
+	chunkSize    int
+	chunkOverlap int
+	chunkUnit    string
+	chunkSource  string
+	chunkOutput  string
+
+	chunkCmd = &cobra.Command{
+		Use:   "chunk [json_file]",
+		Short: "Split OCR JSON output into chunks for retrieval",
+		Long: `Split OCR JSON output into chunks for embedding and retrieval, written as JSON Lines.
+
+Chunks never span a heading, so every chunk belongs to one section and carries the
+path of headings above it. Sections longer than --size are packed into several
+chunks that repeat the last --overlap words of the previous chunk. Tables, code
+fences and math blocks are never cut; one that is larger than --size becomes a
+chunk of its own. Token counts are estimated at four characters per token.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			chunkDocument(args[0])
+		},
+	}
+)
+
+func init() {
+	chunkCmd.Flags().IntVar(&chunkSize, "size", 512, "Maximum chunk size")
+	chunkCmd.Flags().IntVar(&chunkOverlap, "overlap", 64, "Size of the text repeated between consecutive chunks of a section")
+	chunkCmd.Flags().StringVar(&chunkUnit, "unit", "tokens", "Unit of --size and --overlap: tokens or chars")
+	chunkCmd.Flags().StringVar(&chunkSource, "source", "", "Source document recorded in every chunk (default: the JSON file name)")
+	chunkCmd.Flags().StringVarP(&chunkOutput, "output-file", "o", "", "Output JSONL file path (default is stdout)")
+	chunkCmd.Flags().StringVar(&pageSpec, "pages", "", "Only chunk these pages, e.g. 1-5,9,12-")
+}
+
+// readOCRResult loads OCR JSON output from a file
+func readOCRResult(path string) (*mistral.OCRResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return mistral.ParseOCRResult(data)
+}
+
+// chunkOptions validates the chunk size flags
+func chunkOptions() (chunk.Options, error) {
+	opts := chunk.Options{Size: chunkSize, Overlap: chunkOverlap}
+	switch chunkUnit {
+	case "tokens":
+		opts.Unit = chunk.Tokens
+	case "chars":
+		opts.Unit = chunk.Chars
+	default:
+		return opts, fmt.Errorf("unsupported unit '%s' (expected tokens or chars)", chunkUnit)
+	}
+	if chunkSize < 1 {
+		return opts, fmt.Errorf("--size must be at least 1")
+	}
+	if chunkOverlap < 0 || chunkOverlap >= chunkSize {
+		return opts, fmt.Errorf("--overlap must be between 0 and --size")
+	}
+	return opts, nil
+}
+
+// chunkResult splits the pages of result into chunks tagged with source
+func chunkResult(result *mistral.OCRResult, source string, opts chunk.Options) []chunk.Chunk {
+	pages := make([]chunk.Page, len(result.Pages))
+	for i, page := range result.Pages {
+		pages[i] = chunk.Page{Number: page.Index + 1, Markdown: page.Markdown}
+	}
+
+	chunks := chunk.Split(pages, opts)
+	for i := range chunks {
+		chunks[i].Source = source
+	}
+	return chunks
+}
+
+func chunkDocument(jsonFile string) {
+	opts, err := chunkOptions()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	sel, err := parsePageSelection(pageSpec)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	result, err := readOCRResult(jsonFile)
+	if err != nil {
+		fmt.Printf("Error reading JSON file: %v\n", err)
+		os.Exit(1)
+	}
+	filterPages(result, sel)
+
+	source := chunkSource
+	if source == "" {
+		source = filepath.Base(jsonFile)
+	}
+	chunks := chunkResult(result, source, opts)
+
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	for _, c := range chunks {
+		if err := enc.Encode(c); err != nil {
+			fmt.Printf("Error formatting JSON: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if chunkOutput == "" {
+		os.Stdout.Write(out.Bytes())
+		return
+	}
+
+	if dir := filepath.Dir(chunkOutput); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			fmt.Printf("Error creating output directory: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := writeOutputFile(chunkOutput, out.Bytes()); err != nil {
+		fmt.Printf("Error writing output file: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %d chunks from %d pages to %s\n", len(chunks), len(result.Pages), chunkOutput)
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -8,6 +9,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
 	"github.com/spf13/cobra"
 )
 
@@ -18,104 +20,139 @@ var (
 	includePageBreaks bool
 	titleFromFilename bool
 	singleFile        bool
+	convertFormat     string
 
 	convertCmd = &cobra.Command{
 		Use:   "convert [json_file]",
-		Short: "Convert OCR JSON output to Markdown",
+		Short: "Convert OCR JSON output to Markdown, HTML or plain text",
 		Long: `Convert OCR JSON output from Mistral AI to Markdown format.
-The tool will extract text and structure from the JSON output and create Markdown files.`,
+The tool will extract text and structure from the JSON output and create Markdown files.
+
+Other formats are selected with --format:
+  html   a single standalone HTML document with a table of contents; extracted
+         images are embedded unless --images-dir is given
+  txt    plain text with the Markdown syntax stripped
+  jsonl  one JSON object per page with its text, markdown, dimensions and image count`,
 		Args: cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			jsonFile := args[0]
-			convertJSONToMarkdown(jsonFile)
+			convertJSONToMarkdown(cmd.Context(), jsonFile)
 		},
 	}
 )
 
 func init() {
 	convertCmd.Flags().StringVarP(&markdownDir, "output-dir", "d", "markdown_output", "Directory to store markdown files")
-	convertCmd.Flags().StringVarP(&markdownFile, "output-file", "o", "", "Output filename for single file mode (default: document.md)")
+	convertCmd.Flags().StringVarP(&markdownFile, "output-file", "o", "", "Output filename for single file mode (default: document.<format extension>)")
 	convertCmd.Flags().BoolVar(&includeImages, "images", false, "Include images in markdown (if available)")
 	convertCmd.Flags().BoolVar(&includePageBreaks, "page-breaks", true, "Include page break indicators between pages")
 	convertCmd.Flags().BoolVar(&titleFromFilename, "title-from-filename", true, "Use filename as document title")
 	convertCmd.Flags().BoolVar(&singleFile, "single-file", false, "Create a single markdown file instead of one per page")
+	convertCmd.Flags().StringVar(&pageSpec, "pages", "", "Only convert these pages, e.g. 1-5,9,12-")
+	convertCmd.Flags().StringVar(&imagesDir, "images-dir", "", "Write images to this directory (relative to --output-dir) and link them instead of inlining")
+	convertCmd.Flags().StringVar(&convertFormat, "format", "markdown", "Output format: markdown, html, txt or jsonl")
 
 	// If output file is specified, enable single file mode
 	convertCmd.PreRun = func(cmd *cobra.Command, args []string) {
 		if markdownFile != "" {
 			singleFile = true
 		}
+		// Formats without a per-page renderer are always written as one file
+		if format, ok := outputFormats[convertFormat]; ok && format.page == nil {
+			singleFile = true
+		}
+		// HTML is a standalone document, so images are embedded by default
+		if convertFormat == "html" && imagesDir == "" {
+			includeImages = true
+		}
 	}
 }
 
-// OCRResponse represents the structure of Mistral OCR API response
-type OCRResponse struct {
-	Pages []struct {
-		Index    int    `json:"index"`
-		Markdown string `json:"markdown"`
-		Image    string `json:"image,omitempty"`
-		Images   []struct {
-			ID           string `json:"id"`
-			TopLeftX     int    `json:"top_left_x"`
-			TopLeftY     int    `json:"top_left_y"`
-			BottomRightX int    `json:"bottom_right_x"`
-			BottomRightY int    `json:"bottom_right_y"`
-			ImageBase64  string `json:"image_base64"`
-		} `json:"images,omitempty"`
-		Dimensions struct {
-			DPI    int `json:"dpi"`
-			Height int `json:"height"`
-			Width  int `json:"width"`
-		} `json:"dimensions,omitempty"`
-	} `json:"pages"`
-	Metadata struct {
-		Title        string `json:"title,omitempty"`
-		Author       string `json:"author,omitempty"`
-		CreationDate string `json:"creation_date,omitempty"`
-		PageCount    int    `json:"page_count,omitempty"`
-	} `json:"metadata,omitempty"`
+// outputFormat is a format convert can write
+type outputFormat struct {
+	ext string
+	// render combines all pages into one document
+	render func(result *mistral.OCRResult, sourceFile, outputDir, linkDir string) (string, error)
+	// page renders a single page; formats without it are always written as one file
+	page func(page mistral.Page, outputDir, linkDir string) (string, error)
+}
+
+var outputFormats = map[string]outputFormat{
+	"markdown": {ext: ".md", render: renderMarkdown, page: pageMarkdown},
+	"html":     {ext: ".html", render: renderHTML},
+	"txt":      {ext: ".txt", render: renderText, page: pageText},
+	"jsonl":    {ext: ".jsonl", render: renderJSONL},
 }
 
 // replaceImageReferences replaces image references in markdown content with base64 data
-// Format: ![img-id.ext](img-id.ext) becomes ![img-id.ext](data:image/jpeg;base64,DATA)
-func replaceImageReferences(content string, images []OCRResponse_Image) string {
+// Format: ![img-id.ext](img-id.ext) becomes ![img-id.ext](data:image/png;base64,DATA)
+func replaceImageReferences(content string, images []mistral.Image) string {
 	if !includeImages || len(images) == 0 {
 		return content
 	}
 
-	// Create a map of image IDs to their base64 data
+	// Create a map of image IDs to their data URIs
 	imageMap := make(map[string]string)
 	for _, img := range images {
 		if img.ImageBase64 != "" {
-			imgData := img.ImageBase64
-			if !strings.HasPrefix(imgData, "data:") {
-				imgData = "data:image/jpeg;base64," + imgData
+			uri, err := imageDataURI(img)
+			if err != nil {
+				continue
 			}
-			imageMap[img.ID] = imgData
+			imageMap[img.ID] = uri
 		}
 	}
 
-	// Replace all image references with base64 data
-	for id, base64Data := range imageMap {
+	return rewriteImageReferences(content, imageMap)
+}
+
+// rewriteImageReferences points every ![id](id) reference at targets[id]
+func rewriteImageReferences(content string, targets map[string]string) string {
+	for id, target := range targets {
 		// Escape special characters in the ID for regex
 		escapedID := regexp.QuoteMeta(id)
 		pattern := fmt.Sprintf(`!\[%s\]\(%s\)`, escapedID, escapedID)
-		replacement := fmt.Sprintf(`![%s](%s)`, id, base64Data)
+		replacement := fmt.Sprintf(`![%s](%s)`, id, target)
 
 		re := regexp.MustCompile(pattern)
-		content = re.ReplaceAllString(content, replacement)
+		content = re.ReplaceAllLiteralString(content, replacement)
 	}
 
 	return content
 }
 
-// OCRResponse_Image is a helper type for the replaceImageReferences function
-type OCRResponse_Image struct {
-	ID          string
-	ImageBase64 string
+// pageMarkdown returns the markdown of page with image references handled as
+// requested: written to the images directory, inlined, or left untouched.
+// outputDir is the markdown output directory and linkDir the directory of
+// the file the markdown is written to.
+func pageMarkdown(page mistral.Page, outputDir, linkDir string) (string, error) {
+	if imagesDir != "" {
+		links, err := writePageImages(page, outputDir, linkDir)
+		if err != nil {
+			return "", err
+		}
+		return rewriteImageReferences(page.Markdown, links), nil
+	}
+
+	if includeImages {
+		return replaceImageReferences(page.Markdown, page.Images), nil
+	}
+	return page.Markdown, nil
 }
 
-func convertJSONToMarkdown(jsonFile string) {
+func convertJSONToMarkdown(ctx context.Context, jsonFile string) {
+	format, ok := outputFormats[convertFormat]
+	if !ok {
+		fmt.Printf("Error: unsupported format '%s' (expected markdown, html, txt or jsonl)\n", convertFormat)
+		os.Exit(1)
+	}
+
+	sel, err := parsePageSelection(pageSpec)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Read JSON file
 	data, err := os.ReadFile(jsonFile)
 	if err != nil {
@@ -124,7 +161,7 @@ func convertJSONToMarkdown(jsonFile string) {
 	}
 
 	// Parse JSON
-	var ocrResponse OCRResponse
+	var ocrResponse mistral.OCRResult
 	if err := json.Unmarshal(data, &ocrResponse); err != nil {
 		fmt.Printf("Error parsing JSON: %v\n", err)
 
@@ -140,6 +177,9 @@ func convertJSONToMarkdown(jsonFile string) {
 		os.Exit(1)
 	}
 
+	// Only keep the selected pages
+	filterPages(&ocrResponse, sel)
+
 	// Create output directory if it doesn't exist
 	if err := os.MkdirAll(markdownDir, 0755); err != nil {
 		fmt.Printf("Error creating output directory: %v\n", err)
@@ -147,68 +187,8 @@ func convertJSONToMarkdown(jsonFile string) {
 	}
 
 	if singleFile {
-		// Process all pages into a single markdown file
-		var combined strings.Builder
-		title := "Document"
-
-		// Use metadata title if available
-		if ocrResponse.Metadata.Title != "" {
-			title = ocrResponse.Metadata.Title
-		} else if titleFromFilename {
-			// Use filename without extension
-			base := filepath.Base(jsonFile)
-			title = strings.TrimSuffix(base, filepath.Ext(base))
-		}
-
-		combined.WriteString(fmt.Sprintf("# %s\n\n", title))
-
-		// Add metadata if available
-		if ocrResponse.Metadata.Author != "" || ocrResponse.Metadata.CreationDate != "" {
-			combined.WriteString("## Document Metadata\n\n")
-			if ocrResponse.Metadata.Author != "" {
-				combined.WriteString(fmt.Sprintf("**Author:** %s\n\n", ocrResponse.Metadata.Author))
-			}
-			if ocrResponse.Metadata.CreationDate != "" {
-				combined.WriteString(fmt.Sprintf("**Creation Date:** %s\n\n", ocrResponse.Metadata.CreationDate))
-			}
-			if ocrResponse.Metadata.PageCount > 0 {
-				combined.WriteString(fmt.Sprintf("**Page Count:** %d\n\n", ocrResponse.Metadata.PageCount))
-			}
-		}
-
-		// Process each page
-		for i, page := range ocrResponse.Pages {
-			// Add page header
-			combined.WriteString(fmt.Sprintf("## Page %d\n\n", page.Index+1))
-
-			// Convert page images to OCRResponse_Image format
-			var pageImages []OCRResponse_Image
-			for _, img := range page.Images {
-				pageImages = append(pageImages, OCRResponse_Image{
-					ID:          img.ID,
-					ImageBase64: img.ImageBase64,
-				})
-			}
-
-			// Replace image references in markdown content if includeImages is true
-			pageContent := page.Markdown
-			if includeImages {
-				pageContent = replaceImageReferences(pageContent, pageImages)
-			}
-
-			// Add page content
-			combined.WriteString(pageContent)
-			combined.WriteString("\n\n")
-
-			// Add page separator if not the last page
-			if includePageBreaks && i < len(ocrResponse.Pages)-1 {
-				combined.WriteString("\n\n---\n\n")
-			}
-		}
-
-		// Write combined markdown file
 		// Use custom filename if provided, otherwise use default
-		filename := "document.md"
+		filename := "document" + format.ext
 		if markdownFile != "" {
 			// If markdownFile contains directory components, ensure they exist
 			dir := filepath.Dir(markdownFile)
@@ -222,43 +202,106 @@ func convertJSONToMarkdown(jsonFile string) {
 		}
 		outputFilePath := filepath.Join(markdownDir, filename)
 
-		if err := os.WriteFile(outputFilePath, []byte(combined.String()), 0644); err != nil {
-			fmt.Printf("Error writing markdown file: %v\n", err)
+		// Process all pages into a single file
+		combined, err := format.render(&ocrResponse, jsonFile, markdownDir, filepath.Dir(outputFilePath))
+		if err != nil {
+			fmt.Printf("Error rendering %s: %v\n", convertFormat, err)
+			os.Exit(1)
+		}
+		exitIfInterrupted(ctx)
+
+		// Write combined file
+		if err := writeOutputFile(outputFilePath, []byte(combined)); err != nil {
+			fmt.Printf("Error writing %s file: %v\n", convertFormat, err)
 			os.Exit(1)
 		}
 
-		fmt.Printf("Created single markdown file: %s\n", outputFilePath)
+		fmt.Printf("Created single %s file: %s\n", convertFormat, outputFilePath)
 	} else {
 		// Process each page into a separate file
 		for _, page := range ocrResponse.Pages {
+			exitIfInterrupted(ctx)
+
 			// Use page index as the filename
-			filename := fmt.Sprintf("%d.md", page.Index)
+			filename := fmt.Sprintf("%d%s", page.Index, format.ext)
 			outputFilePath := filepath.Join(markdownDir, filename)
 
-			// Convert page images to OCRResponse_Image format
-			var pageImages []OCRResponse_Image
-			for _, img := range page.Images {
-				pageImages = append(pageImages, OCRResponse_Image{
-					ID:          img.ID,
-					ImageBase64: img.ImageBase64,
-				})
-			}
-
 			// Get page content with image references replaced if needed
-			markdownContent := page.Markdown
-			if includeImages {
-				markdownContent = replaceImageReferences(markdownContent, pageImages)
+			content, err := format.page(page, markdownDir, markdownDir)
+			if err != nil {
+				fmt.Printf("Error processing images of page %d: %v\n", page.Index+1, err)
+				os.Exit(1)
 			}
 
-			if err := os.WriteFile(outputFilePath, []byte(markdownContent), 0644); err != nil {
-				fmt.Printf("Error writing markdown file %s: %v\n", outputFilePath, err)
+			if err := writeOutputFile(outputFilePath, []byte(content)); err != nil {
+				fmt.Printf("Error writing %s file %s: %v\n", convertFormat, outputFilePath, err)
 				os.Exit(1)
 			}
 
-			fmt.Printf("Created markdown file: %s\n", outputFilePath)
+			fmt.Printf("Created %s file: %s\n", convertFormat, outputFilePath)
 		}
 	}
 
-	fmt.Printf("Successfully converted %s to markdown files in %s/\n", jsonFile, markdownDir)
+	fmt.Printf("Successfully converted %s to %s in %s/\n", jsonFile, convertFormat, markdownDir)
 	fmt.Printf("Total pages: %d\n", len(ocrResponse.Pages))
 }
+
+// renderMarkdown combines all pages of result into a single markdown document.
+// sourceFile is used for the title when the result carries no metadata title;
+// outputDir and linkDir are passed on to pageMarkdown.
+func renderMarkdown(result *mistral.OCRResult, sourceFile, outputDir, linkDir string) (string, error) {
+	var combined strings.Builder
+	combined.WriteString(fmt.Sprintf("# %s\n\n", documentTitle(result, sourceFile)))
+
+	// Add metadata if available
+	if result.Metadata.Author != "" || result.Metadata.CreationDate != "" {
+		combined.WriteString("## Document Metadata\n\n")
+		if result.Metadata.Author != "" {
+			combined.WriteString(fmt.Sprintf("**Author:** %s\n\n", result.Metadata.Author))
+		}
+		if result.Metadata.CreationDate != "" {
+			combined.WriteString(fmt.Sprintf("**Creation Date:** %s\n\n", result.Metadata.CreationDate))
+		}
+		if result.Metadata.PageCount > 0 {
+			combined.WriteString(fmt.Sprintf("**Page Count:** %d\n\n", result.Metadata.PageCount))
+		}
+	}
+
+	// Process each page
+	for i, page := range result.Pages {
+		// Add page header
+		combined.WriteString(fmt.Sprintf("## Page %d\n\n", page.Index+1))
+
+		// Replace image references in markdown content if requested
+		pageContent, err := pageMarkdown(page, outputDir, linkDir)
+		if err != nil {
+			return "", fmt.Errorf("page %d: %v", page.Index+1, err)
+		}
+
+		// Add page content
+		combined.WriteString(pageContent)
+		combined.WriteString("\n\n")
+
+		// Add page separator if not the last page
+		if includePageBreaks && i < len(result.Pages)-1 {
+			combined.WriteString("\n\n---\n\n")
+		}
+	}
+
+	return combined.String(), nil
+}
+
+// documentTitle returns the metadata title of result, falling back to the
+// name of sourceFile when --title-from-filename is set
+func documentTitle(result *mistral.OCRResult, sourceFile string) string {
+	// Use metadata title if available
+	if result.Metadata.Title != "" {
+		return result.Metadata.Title
+	}
+	if titleFromFilename {
+		// Use filename without extension
+		base := filepath.Base(sourceFile)
+		return strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	return "Document"
+}
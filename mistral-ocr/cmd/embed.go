@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/chunk"
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+	"github.com/spf13/cobra"
+)
+
+var (
+	embeddingModel string
+	embedBatchSize int
+	embedOutput    string
+
+	embedCmd = &cobra.Command{
+		Use:   "embed [json_or_jsonl_file]",
+		Short: "Generate embeddings for OCR output or chunks",
+		Long: `Generate embeddings with the Mistral embeddings API and write them as JSON Lines,
+one chunk per line with its metadata and an "embedding" vector.
+
+The input is either OCR JSON output, which is chunked first using the same options as
+the chunk command, or a JSONL file written by the chunk command.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			embedFile(cmd.Context(), args[0])
+		},
+	}
+)
+
+func init() {
+	embedCmd.Flags().StringVar(&embeddingModel, "embedding-model", mistral.DefaultEmbeddingModel, "Embedding model")
+	embedCmd.Flags().IntVar(&embedBatchSize, "batch-size", mistral.DefaultEmbeddingBatchSize, "Number of chunks sent per embeddings request")
+	embedCmd.Flags().StringVarP(&embedOutput, "output-file", "o", "", "Output JSONL file path (default is stdout)")
+	embedCmd.Flags().IntVar(&chunkSize, "size", 512, "Maximum chunk size when chunking OCR JSON")
+	embedCmd.Flags().IntVar(&chunkOverlap, "overlap", 64, "Overlap between chunks when chunking OCR JSON")
+	embedCmd.Flags().StringVar(&chunkUnit, "unit", "tokens", "Unit of --size and --overlap: tokens or chars")
+	embedCmd.Flags().StringVar(&chunkSource, "source", "", "Source document recorded in every chunk of OCR JSON input (default: the JSON file name)")
+	embedCmd.Flags().StringVar(&pageSpec, "pages", "", "Only embed these pages of OCR JSON input, e.g. 1-5,9,12-")
+}
+
+// embeddedChunk is a chunk with its embedding
+type embeddedChunk struct {
+	chunk.Chunk
+	Model     string    `json:"model"`
+	Embedding []float64 `json:"embedding"`
+}
+
+// loadChunks reads OCR JSON, which is chunked, or chunk JSONL
+func loadChunks(path string) ([]chunk.Chunk, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var probe struct {
+		Pages json.RawMessage `json:"pages"`
+	}
+	if json.Valid(data) && json.Unmarshal(data, &probe) == nil && probe.Pages != nil {
+		opts, err := chunkOptions()
+		if err != nil {
+			return nil, err
+		}
+		sel, err := parsePageSelection(pageSpec)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := mistral.ParseOCRResult(data)
+		if err != nil {
+			return nil, err
+		}
+		filterPages(result, sel)
+
+		source := chunkSource
+		if source == "" {
+			source = filepath.Base(path)
+		}
+		return chunkResult(result, source, opts), nil
+	}
+
+	var chunks []chunk.Chunk
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var c chunk.Chunk
+		if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if c.Text == "" {
+			return nil, fmt.Errorf("line %d: chunk has no text", line)
+		}
+		chunks = append(chunks, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
+func embedFile(ctx context.Context, path string) {
+	chunks, err := loadChunks(path)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", path, err)
+		os.Exit(1)
+	}
+	if len(chunks) == 0 {
+		fmt.Println("Error: no chunks to embed")
+		os.Exit(1)
+	}
+
+	inputs := make([]string, len(chunks))
+	for i, c := range chunks {
+		inputs[i] = c.Text
+	}
+
+	client := newClient()
+	fmt.Fprintf(os.Stderr, "Embedding %d chunks with %s\n", len(chunks), embeddingModel)
+	vectors, usage, err := client.EmbedBatched(ctx, embeddingModel, inputs, embedBatchSize, func(done int) {
+		fmt.Fprintf(os.Stderr, "Embedded %d/%d chunks\n", done, len(inputs))
+	})
+	if err != nil {
+		exitIfInterrupted(ctx)
+		fmt.Printf("Error generating embeddings: %v\n", err)
+		os.Exit(exitCode(err))
+	}
+
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	for i, c := range chunks {
+		if err := enc.Encode(embeddedChunk{Chunk: c, Model: embeddingModel, Embedding: vectors[i]}); err != nil {
+			fmt.Printf("Error formatting JSON: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if embedOutput == "" {
+		os.Stdout.Write(out.Bytes())
+		return
+	}
+
+	if dir := filepath.Dir(embedOutput); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			fmt.Printf("Error creating output directory: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := writeOutputFile(embedOutput, out.Bytes()); err != nil {
+		fmt.Printf("Error writing output file: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %d embeddings (%d tokens) to %s\n", len(chunks), usage.TotalTokens, embedOutput)
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+)
+
+// Exit codes of commands that fail on an API error, so scripts can tell
+// failures apart without parsing messages. 130 is used for interrupts.
+const (
+	exitFailure        = 1
+	exitAuth           = 3
+	exitQuota          = 4
+	exitInvalidRequest = 5
+	exitServer         = 6
+)
+
+// exitCode returns the exit code for err by the category of its API error
+func exitCode(err error) int {
+	var apiErr *mistral.APIError
+	if !errors.As(err, &apiErr) {
+		return exitFailure
+	}
+
+	switch apiErr.Category() {
+	case mistral.CategoryAuth:
+		return exitAuth
+	case mistral.CategoryQuota:
+		return exitQuota
+	case mistral.CategoryInvalidRequest:
+		return exitInvalidRequest
+	case mistral.CategoryServer:
+		return exitServer
+	default:
+		return exitFailure
+	}
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/jsonschema"
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+	"github.com/spf13/cobra"
+)
+
+var (
+	schemaFile     string
+	bboxSchemaFile string
+	schemaName     string
+	strictSchema   bool
+	skipValidation bool
+	extractOutput  string
+
+	extractCmd = &cobra.Command{
+		Use:   "extract [file_or_url]",
+		Short: "Extract structured fields from a document using a JSON Schema",
+		Long: `Extract structured data, e.g. invoice fields, from a document. The JSON Schema is sent
+to Mistral as the document annotation format, the returned annotation is validated
+against the schema and written as JSON.
+
+Note that the API only annotates the first pages of a document (8 at the time of
+writing); use --pages to pick the pages that contain the fields.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			extractFields(cmd.Context(), args[0])
+		},
+	}
+)
+
+func init() {
+	extractCmd.Flags().StringVarP(&schemaFile, "schema", "s", "", "JSON Schema file describing the fields to extract (required)")
+	extractCmd.Flags().StringVar(&bboxSchemaFile, "bbox-schema", "", "JSON Schema file for annotating each extracted image (optional)")
+	extractCmd.Flags().StringVar(&schemaName, "schema-name", "", "Name of the schema sent to the API (default: schema file name)")
+	extractCmd.Flags().BoolVar(&strictSchema, "strict", false, "Ask the API to follow the schema strictly")
+	extractCmd.Flags().BoolVar(&skipValidation, "skip-validation", false, "Write the annotation even if it does not match the schema")
+	extractCmd.Flags().StringVarP(&extractOutput, "output-file", "o", "", "Output JSON file path (default is stdout)")
+	extractCmd.Flags().StringVar(&pageSpec, "pages", "", "Only process these pages, e.g. 1-3")
+	extractCmd.MarkFlagRequired("schema")
+}
+
+var invalidSchemaNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// loadSchemaFile reads and compiles a JSON Schema file and returns it
+// together with the response format sent to the API
+func loadSchemaFile(path, name string) (*jsonschema.Schema, *mistral.ResponseFormat, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	schema, err := jsonschema.Compile(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if name == "" {
+		base := filepath.Base(path)
+		name = strings.TrimSuffix(strings.TrimSuffix(base, filepath.Ext(base)), ".schema")
+	}
+	name = strings.Trim(invalidSchemaNameChars.ReplaceAllString(name, "_"), "_")
+	if name == "" {
+		name = "schema"
+	}
+
+	return schema, mistral.NewJSONSchemaFormat(name, json.RawMessage(data), strictSchema), nil
+}
+
+// imageAnnotation is a bbox annotation tied to the image it describes
+type imageAnnotation struct {
+	Page       int             `json:"page"`
+	ID         string          `json:"id"`
+	Annotation json.RawMessage `json:"annotation"`
+}
+
+// extractResult is written when image annotations were requested as well
+type extractResult struct {
+	Document json.RawMessage   `json:"document"`
+	Images   []imageAnnotation `json:"images"`
+}
+
+// parseAnnotation decodes an annotation and validates it against schema
+func parseAnnotation(annotation string, schema *jsonschema.Schema) (json.RawMessage, error) {
+	if annotation == "" {
+		return nil, fmt.Errorf("the API returned no annotation")
+	}
+	if !json.Valid([]byte(annotation)) {
+		return nil, fmt.Errorf("the API returned an annotation that is not valid JSON")
+	}
+	if !skipValidation {
+		if err := schema.ValidateJSON([]byte(annotation)); err != nil {
+			return nil, err
+		}
+	}
+	return json.RawMessage(annotation), nil
+}
+
+func extractFields(ctx context.Context, fileOrURL string) {
+	schema, format, err := loadSchemaFile(schemaFile, schemaName)
+	if err != nil {
+		fmt.Printf("Error loading schema: %v\n", err)
+		os.Exit(1)
+	}
+	documentAnnotationFormat = format
+
+	var bboxSchema *jsonschema.Schema
+	if bboxSchemaFile != "" {
+		bboxSchema, bboxAnnotationFormat, err = loadSchemaFile(bboxSchemaFile, "")
+		if err != nil {
+			fmt.Printf("Error loading bbox schema: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Progress goes to stderr so the extracted JSON can be piped from stdout
+	logf := func(format string, a ...interface{}) {
+		fmt.Fprintf(os.Stderr, format, a...)
+	}
+
+	client := newClient()
+	respData, err := ocrDocument(ctx, mistralProvider{client: client}, fileOrURL, logf)
+	if err != nil {
+		exitIfInterrupted(ctx)
+		fmt.Printf("Error processing document: %v\n", err)
+		os.Exit(exitCode(err))
+	}
+
+	result, err := mistral.ParseOCRResult(respData)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	document, err := parseAnnotation(result.DocumentAnnotation, schema)
+	if err != nil {
+		fmt.Printf("Error in document annotation: %v\n", err)
+		os.Exit(1)
+	}
+
+	var output interface{} = document
+	if bboxSchema != nil {
+		images := []imageAnnotation{}
+		for _, page := range result.Pages {
+			for _, img := range page.Images {
+				if img.ImageAnnotation == "" {
+					continue
+				}
+				annotation, err := parseAnnotation(img.ImageAnnotation, bboxSchema)
+				if err != nil {
+					fmt.Printf("Error in annotation of image %s on page %d: %v\n", img.ID, page.Index+1, err)
+					os.Exit(1)
+				}
+				images = append(images, imageAnnotation{Page: page.Index + 1, ID: img.ID, Annotation: annotation})
+			}
+		}
+		output = extractResult{Document: document, Images: images}
+	}
+
+	data, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		fmt.Printf("Error formatting JSON: %v\n", err)
+		os.Exit(1)
+	}
+
+	if extractOutput == "" {
+		fmt.Println(string(data))
+		return
+	}
+
+	if dir := filepath.Dir(extractOutput); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			fmt.Printf("Error creating output directory: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := writeOutputFile(extractOutput, append(data, '\n')); err != nil {
+		fmt.Printf("Error writing output file: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Extracted fields saved to %s\n", extractOutput)
+}
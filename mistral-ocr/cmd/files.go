@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+	"github.com/spf13/cobra"
+)
+
+var (
+	filesPurpose string
+	filesSearch  string
+	purgeOlder   string
+	purgePurpose string
+	purgeDryRun  bool
+
+	filesCmd = &cobra.Command{
+		Use:   "files",
+		Short: "List and delete files uploaded to your Mistral account",
+		Long: `Local documents are uploaded to your Mistral account before OCR. process, markdown
+and batch delete their uploads when done unless --keep-upload is given; these commands
+clean up files left behind by earlier runs or other tools.`,
+	}
+
+	filesLsCmd = &cobra.Command{
+		Use:   "ls",
+		Short: "List uploaded files",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			listFiles(cmd.Context())
+		},
+	}
+
+	filesRmCmd = &cobra.Command{
+		Use:   "rm [file_id...]",
+		Short: "Delete uploaded files",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			removeFiles(cmd.Context(), args)
+		},
+	}
+
+	filesPurgeCmd = &cobra.Command{
+		Use:   "purge",
+		Short: "Delete uploaded files older than a given age",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			purgeFiles(cmd.Context())
+		},
+	}
+)
+
+func init() {
+	filesLsCmd.Flags().StringVar(&filesPurpose, "purpose", "", "Only list files uploaded for this purpose, e.g. ocr")
+	filesLsCmd.Flags().StringVar(&filesSearch, "search", "", "Only list files whose name contains this text")
+
+	filesPurgeCmd.Flags().StringVar(&purgeOlder, "older-than", "", "Delete files uploaded longer ago than this age, e.g. 24h or 7d")
+	filesPurgeCmd.Flags().StringVar(&purgePurpose, "purpose", "ocr", "Only delete files uploaded for this purpose (empty for all)")
+	filesPurgeCmd.Flags().BoolVar(&purgeDryRun, "dry-run", false, "Only list the files that would be deleted")
+	filesPurgeCmd.MarkFlagRequired("older-than")
+
+	filesCmd.AddCommand(filesLsCmd)
+	filesCmd.AddCommand(filesRmCmd)
+	filesCmd.AddCommand(filesPurgeCmd)
+}
+
+// printFiles lists files as a table, newest first
+func printFiles(files []mistral.File) {
+	sort.Slice(files, func(i, j int) bool { return files[i].CreatedAt > files[j].CreatedAt })
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCREATED\tSIZE\tPURPOSE\tFILENAME")
+	for _, f := range files {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			f.ID, f.Created().Local().Format("2006-01-02 15:04"), formatBytes(f.Bytes), f.Purpose, f.Filename)
+	}
+	w.Flush()
+}
+
+func listFiles(ctx context.Context) {
+	client := newClient()
+
+	files, err := client.ListAllFiles(ctx, mistral.ListFilesOptions{Purpose: filesPurpose, Search: filesSearch})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(exitCode(err))
+	}
+
+	if len(files) == 0 {
+		fmt.Println("No uploaded files")
+		return
+	}
+
+	var total int64
+	for _, f := range files {
+		total += f.Bytes
+	}
+	printFiles(files)
+	fmt.Printf("%d files, %s\n", len(files), formatBytes(total))
+}
+
+func removeFiles(ctx context.Context, ids []string) {
+	client := newClient()
+
+	var lastErr error
+	for _, id := range ids {
+		if err := client.DeleteFile(ctx, id); err != nil {
+			exitIfInterrupted(ctx)
+			fmt.Printf("Error: %v\n", err)
+			lastErr = err
+			continue
+		}
+		fmt.Printf("Deleted file %s\n", id)
+	}
+
+	if lastErr != nil {
+		os.Exit(exitCode(lastErr))
+	}
+}
+
+func purgeFiles(ctx context.Context) {
+	age, err := parseAge(purgeOlder)
+	if err != nil {
+		fmt.Printf("Error: invalid --older-than value: %v\n", err)
+		os.Exit(1)
+	}
+	cutoff := time.Now().Add(-age)
+
+	client := newClient()
+
+	files, err := client.ListAllFiles(ctx, mistral.ListFilesOptions{Purpose: purgePurpose})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(exitCode(err))
+	}
+
+	var old []mistral.File
+	for _, f := range files {
+		if f.Created().Before(cutoff) {
+			old = append(old, f)
+		}
+	}
+
+	if purgeDryRun {
+		if len(old) > 0 {
+			printFiles(old)
+		}
+		fmt.Printf("Would delete %d of %d listed files (older than %s)\n", len(old), len(files), purgeOlder)
+		return
+	}
+
+	deleted := 0
+	var lastErr error
+	for _, f := range old {
+		if err := client.DeleteFile(ctx, f.ID); err != nil {
+			exitIfInterrupted(ctx)
+			fmt.Printf("Error: %v\n", err)
+			lastErr = err
+			continue
+		}
+		deleted++
+	}
+
+	fmt.Printf("Deleted %d of %d files older than %s\n", deleted, len(old), purgeOlder)
+	if lastErr != nil {
+		os.Exit(exitCode(lastErr))
+	}
+}
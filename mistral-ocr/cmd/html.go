@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/markdown"
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+)
+
+// katexVersion is the KaTeX release loaded from the CDN to typeset math.
+// Without network access the TeX source is shown instead.
+const katexVersion = "0.16.11"
+
+// tocEntry is a heading listed in the table of contents
+type tocEntry struct {
+	ID    string
+	Level int
+	Title template.HTML
+}
+
+// htmlPage is a rendered page of the HTML document
+type htmlPage struct {
+	Number   int
+	ID       string
+	Headings []tocEntry
+	Content  template.HTML
+}
+
+var htmlDocument = template.Must(template.New("document").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/katex@{{.KaTeX}}/dist/katex.min.css">
+<script defer src="https://cdn.jsdelivr.net/npm/katex@{{.KaTeX}}/dist/katex.min.js"></script>
+<script defer src="https://cdn.jsdelivr.net/npm/katex@{{.KaTeX}}/dist/contrib/auto-render.min.js"
+  onload="renderMathInElement(document.body, {delimiters: [{left: '\\[', right: '\\]', display: true}, {left: '\\(', right: '\\)', display: false}], throwOnError: false})"></script>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; line-height: 1.6; color: #24292f; max-width: 52rem; margin: 0 auto; padding: 2rem 1rem; }
+nav.toc { border: 1px solid #d0d7de; border-radius: 6px; padding: 0.5rem 1.5rem; margin-bottom: 2rem; }
+nav.toc ul { list-style: none; padding-left: 1rem; }
+nav.toc > ul { padding-left: 0; }
+a { color: #0969da; text-decoration: none; }
+a:hover { text-decoration: underline; }
+.page-number { font-size: 0.9rem; color: #57606a; border-bottom: 1px solid #d0d7de; }
+table { border-collapse: collapse; margin: 1rem 0; display: block; overflow-x: auto; }
+th, td { border: 1px solid #d0d7de; padding: 0.3rem 0.7rem; }
+tr:nth-child(even) { background: #f6f8fa; }
+pre { background: #f6f8fa; padding: 1rem; overflow-x: auto; border-radius: 6px; }
+code { font-family: ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; font-size: 0.9em; }
+img { max-width: 100%; }
+blockquote { margin-left: 0; padding-left: 1rem; border-left: 4px solid #d0d7de; color: #57606a; }
+div.math { overflow-x: auto; }
+hr.page-break { border: 0; border-top: 2px dashed #d0d7de; margin: 3rem 0; }
+@media print { nav.toc { display: none; } hr.page-break { border: 0; margin: 0; page-break-after: always; } }
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Title}}</h1>
+{{- if or .Metadata.Author .Metadata.CreationDate}}
+<dl class="metadata">
+{{- if .Metadata.Author}}
+<dt>Author</dt><dd>{{.Metadata.Author}}</dd>
+{{- end}}
+{{- if .Metadata.CreationDate}}
+<dt>Creation Date</dt><dd>{{.Metadata.CreationDate}}</dd>
+{{- end}}
+{{- if .Metadata.PageCount}}
+<dt>Page Count</dt><dd>{{.Metadata.PageCount}}</dd>
+{{- end}}
+</dl>
+{{- end}}
+</header>
+<nav class="toc">
+<h2>Contents</h2>
+<ul>
+{{- range .Pages}}
+<li><a href="#{{.ID}}">Page {{.Number}}</a>
+{{- if .Headings}}
+<ul>
+{{- range .Headings}}
+<li class="toc-h{{.Level}}"><a href="#{{.ID}}">{{.Title}}</a></li>
+{{- end}}
+</ul>
+{{- end}}
+</li>
+{{- end}}
+</ul>
+</nav>
+<main>
+{{- range $i, $page := .Pages}}
+{{- if and $.PageBreaks $i}}
+<hr class="page-break">
+{{- end}}
+<section class="page" id="{{$page.ID}}">
+<h2 class="page-number"><a href="#{{$page.ID}}">Page {{$page.Number}}</a></h2>
+{{$page.Content}}
+</section>
+{{- end}}
+</main>
+</body>
+</html>
+`))
+
+// renderHTML renders all pages of result as a standalone HTML document with
+// a table of contents. The arguments are the same as for renderMarkdown.
+func renderHTML(result *mistral.OCRResult, sourceFile, outputDir, linkDir string) (string, error) {
+	var pages []htmlPage
+	for _, page := range result.Pages {
+		content, err := pageMarkdown(page, outputDir, linkDir)
+		if err != nil {
+			return "", fmt.Errorf("page %d: %v", page.Index+1, err)
+		}
+
+		p := htmlPage{Number: page.Index + 1, ID: fmt.Sprintf("page-%d", page.Index+1)}
+		blocks := markdown.Parse(content)
+
+		// Give every top level heading an anchor unique within the document
+		used := make(map[string]int)
+		for i := range blocks {
+			if blocks[i].Kind != markdown.Heading {
+				continue
+			}
+			id := uniqueID(used, p.ID+"-"+slugify(blocks[i].Text))
+			blocks[i].ID = id
+			p.Headings = append(p.Headings, tocEntry{
+				ID:    id,
+				Level: blocks[i].Level,
+				Title: template.HTML(markdown.InlineHTML(blocks[i].Text)),
+			})
+		}
+
+		p.Content = template.HTML(markdown.HTML(blocks))
+		pages = append(pages, p)
+	}
+
+	var out strings.Builder
+	err := htmlDocument.Execute(&out, struct {
+		Title      string
+		KaTeX      string
+		Metadata   mistral.Metadata
+		PageBreaks bool
+		Pages      []htmlPage
+	}{
+		Title:      documentTitle(result, sourceFile),
+		KaTeX:      katexVersion,
+		Metadata:   result.Metadata,
+		PageBreaks: includePageBreaks,
+		Pages:      pages,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error rendering HTML: %v", err)
+	}
+	return out.String(), nil
+}
+
+// uniqueID returns id, or id with the lowest numeric suffix that makes it
+// unique among the IDs in used, and records the result in used. used counts
+// the suffixes already tried for every ID.
+func uniqueID(used map[string]int, id string) string {
+	candidate := id
+	for used[candidate] > 0 {
+		candidate = fmt.Sprintf("%s-%d", id, used[id])
+		used[id]++
+	}
+	used[candidate] = 1
+	return candidate
+}
+
+// slugify turns heading text into an anchor fragment
+func slugify(text string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r > 127:
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		default:
+			dash = true
+		}
+	}
+	if b.Len() == 0 {
+		return "section"
+	}
+	return b.String()
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUniqueID(t *testing.T) {
+	tests := []struct {
+		name string
+		ids  []string
+		want []string
+	}{
+		{name: "distinct", ids: []string{"intro", "scope"}, want: []string{"intro", "scope"}},
+		{name: "repeated", ids: []string{"intro", "intro", "intro"}, want: []string{"intro", "intro-1", "intro-2"}},
+		{name: "suffix taken by a heading", ids: []string{"intro", "intro", "intro-1"}, want: []string{"intro", "intro-1", "intro-1-1"}},
+		{name: "suffix taken first", ids: []string{"intro-1", "intro", "intro"}, want: []string{"intro-1", "intro", "intro-2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			used := make(map[string]int)
+			var got []string
+			for _, id := range tt.ids {
+				got = append(got, uniqueID(used, id))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unique IDs = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+)
+
+// imagesDir is the --images-dir flag. Relative paths are resolved against
+// the markdown output directory.
+var imagesDir string
+
+// imageExtensions maps sniffed MIME types to file extensions
+var imageExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+	"image/bmp":  ".bmp",
+}
+
+// decodeImage returns the bytes of an extracted image and their MIME type,
+// which is sniffed from the content instead of trusting any data URI prefix
+func decodeImage(img mistral.Image) ([]byte, string, error) {
+	encoded := img.ImageBase64
+	if strings.HasPrefix(encoded, "data:") {
+		if i := strings.Index(encoded, ","); i >= 0 {
+			encoded = encoded[i+1:]
+		}
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		if data, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(encoded, "=")); err != nil {
+			return nil, "", fmt.Errorf("image %s: invalid base64 data: %v", img.ID, err)
+		}
+	}
+
+	return data, http.DetectContentType(data), nil
+}
+
+// imageExtension picks the file extension for an image of the given MIME type
+func imageExtension(mimeType, id string) string {
+	if ext, ok := imageExtensions[mimeType]; ok {
+		return ext
+	}
+	if ext := filepath.Ext(id); ext != "" {
+		return strings.ToLower(ext)
+	}
+	return ".bin"
+}
+
+// imageDataURI returns the image as a data URI carrying its real MIME type
+func imageDataURI(img mistral.Image) (string, error) {
+	data, mimeType, err := decodeImage(img)
+	if err != nil {
+		return "", err
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// resolveImagesDir returns the directory image files are written to
+func resolveImagesDir(outputDir string) string {
+	if filepath.IsAbs(imagesDir) {
+		return imagesDir
+	}
+	return filepath.Join(outputDir, imagesDir)
+}
+
+// writePageImages writes the images of page to the images directory as
+// page-<n>-img-<i>.<ext> and returns their paths relative to linkDir, the
+// directory of the document that links to them, keyed by image ID
+func writePageImages(page mistral.Page, outputDir, linkDir string) (map[string]string, error) {
+	links := make(map[string]string)
+	if len(page.Images) == 0 {
+		return links, nil
+	}
+
+	dir := resolveImagesDir(outputDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating images directory: %v", err)
+	}
+
+	for i, img := range page.Images {
+		if img.ImageBase64 == "" {
+			continue
+		}
+
+		data, mimeType, err := decodeImage(img)
+		if err != nil {
+			return nil, err
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("page-%d-img-%d%s", page.Index+1, i, imageExtension(mimeType, img.ID)))
+		if err := writeOutputFile(path, data); err != nil {
+			return nil, fmt.Errorf("error writing image %s: %v", path, err)
+		}
+
+		links[img.ID] = filepath.ToSlash(relativeLink(linkDir, path))
+	}
+	return links, nil
+}
+
+// relativeLink returns target relative to dir, or target itself if no relative path exists
+func relativeLink(dir, target string) string {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return target
+	}
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return target
+	}
+	rel, err := filepath.Rel(absDir, absTarget)
+	if err != nil {
+		return target
+	}
+	return rel
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// partialOutputs records the files written by the running command so they
+// can be removed again if the command is interrupted before it finishes
+var partialOutputs outputTracker
+
+type outputTracker struct {
+	mu    sync.Mutex
+	paths []string
+}
+
+// track remembers path as an output of the current command
+func (t *outputTracker) track(path string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.paths = append(t.paths, path)
+}
+
+// forget stops tracking paths, keeping them if the command is interrupted later
+func (t *outputTracker) forget(paths ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	kept := t.paths[:0]
+	for _, tracked := range t.paths {
+		if !slices.Contains(paths, tracked) {
+			kept = append(kept, tracked)
+		}
+	}
+	t.paths = kept
+}
+
+// removeAll deletes every tracked output and forgets about them
+func (t *outputTracker) removeAll() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, path := range t.paths {
+		os.Remove(path)
+	}
+	t.paths = nil
+}
+
+// writeOutputFile writes data to a temporary file next to path and renames it
+// into place, so an interrupted write never leaves a truncated file. Only
+// files this run creates are tracked as partial outputs; an existing file is
+// replaced but not removed on interrupt.
+func writeOutputFile(path string, data []byte) error {
+	_, statErr := os.Lstat(path)
+	created := os.IsNotExist(statErr)
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if created {
+		partialOutputs.track(path)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// exitIfInterrupted removes partial outputs and exits when ctx has been cancelled
+func exitIfInterrupted(ctx context.Context) {
+	if ctx.Err() == nil {
+		return
+	}
+
+	partialOutputs.removeAll()
+	fmt.Println("Interrupted, partial output removed")
+	os.Exit(130)
+}
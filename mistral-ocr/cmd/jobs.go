@@ -0,0 +1,652 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+	"github.com/spf13/cobra"
+)
+
+var (
+	jobsDir      string
+	jobResultOut string
+
+	jobsCmd = &cobra.Command{
+		Use:   "jobs",
+		Short: "Inspect asynchronous jobs of the HTTP server",
+		Long: `Jobs submitted to POST /v1/jobs of the serve command are kept on disk, one directory
+per job holding its status, the uploaded document and the OCR result. These commands
+read that directory directly and work whether or not the server is running.`,
+	}
+
+	jobsLsCmd = &cobra.Command{
+		Use:   "ls",
+		Short: "List jobs",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			listJobs()
+		},
+	}
+
+	jobsShowCmd = &cobra.Command{
+		Use:   "show [job_id]",
+		Short: "Show the status of a job",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			showJob(args[0])
+		},
+	}
+
+	jobsResultCmd = &cobra.Command{
+		Use:   "result [job_id]",
+		Short: "Print or save the OCR JSON result of a job",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			jobResult(args[0])
+		},
+	}
+)
+
+func init() {
+	jobsCmd.PersistentFlags().StringVar(&jobsDir, "jobs-dir", "", "Job directory (defaults to MISTRAL_OCR_JOBS_DIR env variable or jobs/ in the cache dir)")
+	jobsResultCmd.Flags().StringVarP(&jobResultOut, "output-file", "o", "", "Output JSON file path (default is stdout)")
+
+	jobsCmd.AddCommand(jobsLsCmd)
+	jobsCmd.AddCommand(jobsShowCmd)
+	jobsCmd.AddCommand(jobsResultCmd)
+}
+
+// Job states
+const (
+	jobQueued    = "queued"
+	jobRunning   = "running"
+	jobSucceeded = "succeeded"
+	jobFailed    = "failed"
+)
+
+// job is an asynchronous OCR request. It is stored as job.json in the job's directory.
+type job struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	// Source is the uploaded file name or the URL
+	Source string `json:"source"`
+	URL    string `json:"url,omitempty"`
+	// Input is the name of the uploaded document within the job directory
+	Input        string     `json:"input,omitempty"`
+	Pages        string     `json:"pages,omitempty"`
+	Format       string     `json:"format"`
+	Webhook      string     `json:"webhook,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	PageCount    int        `json:"page_count,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+	WebhookError string     `json:"webhook_error,omitempty"`
+}
+
+var jobIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// newJobID returns a random job ID
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// resolveJobsDir returns the job directory from flag, environment or the cache dir
+func resolveJobsDir() (string, error) {
+	if dir := flagOrEnv(jobsDir, "MISTRAL_OCR_JOBS_DIR"); dir != "" {
+		return dir, nil
+	}
+
+	dir, err := resolveCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "jobs"), nil
+}
+
+// jobStore persists jobs on disk and queues the pending ones for workers
+type jobStore struct {
+	dir string
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  []string
+	closed bool
+}
+
+func openJobStore() (*jobStore, error) {
+	dir, err := resolveJobsDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	s := &jobStore{dir: dir}
+	s.cond = sync.NewCond(&s.mu)
+	return s, nil
+}
+
+// path returns the path of a file in the directory of job id
+func (s *jobStore) path(id string, name ...string) string {
+	return filepath.Join(append([]string{s.dir, id}, name...)...)
+}
+
+// save writes j atomically
+func (s *jobStore) save(j *job) error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path(j.ID), "job.json", data)
+}
+
+func (s *jobStore) load(id string) (*job, error) {
+	if !jobIDPattern.MatchString(id) {
+		return nil, os.ErrNotExist
+	}
+	data, err := os.ReadFile(s.path(id, "job.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var j job
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("job %s: %v", id, err)
+	}
+	return &j, nil
+}
+
+// list returns all jobs, oldest first
+func (s *jobStore) list() ([]*job, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []*job
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if j, err := s.load(e.Name()); err == nil {
+			jobs = append(jobs, j)
+		}
+	}
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].CreatedAt.Before(jobs[k].CreatedAt) })
+	return jobs, nil
+}
+
+// recover queues jobs left queued or running by a previous server
+func (s *jobStore) recover() (int, error) {
+	jobs, err := s.list()
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, j := range jobs {
+		if j.Status != jobQueued && j.Status != jobRunning {
+			continue
+		}
+		j.Status = jobQueued
+		j.StartedAt = nil
+		if err := s.save(j); err != nil {
+			return n, err
+		}
+		s.enqueue(j.ID)
+		n++
+	}
+	return n, nil
+}
+
+func (s *jobStore) enqueue(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue = append(s.queue, id)
+	s.cond.Signal()
+}
+
+// next blocks until a job is queued and returns its ID, or false once the store is closed
+func (s *jobStore) next() (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.queue) == 0 && !s.closed {
+		s.cond.Wait()
+	}
+	if s.closed {
+		return "", false
+	}
+	id := s.queue[0]
+	s.queue = s.queue[1:]
+	return id, true
+}
+
+// queued returns the number of jobs waiting for a worker
+func (s *jobStore) queued() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queue)
+}
+
+// close wakes up all waiting workers and stops handing out jobs
+func (s *jobStore) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.cond.Broadcast()
+}
+
+// prune removes finished jobs that finished longer than maxAge ago and
+// returns how many were removed
+func (s *jobStore) prune(maxAge time.Duration) (int, error) {
+	jobs, err := s.list()
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	cutoff := time.Now().Add(-maxAge)
+	for _, j := range jobs {
+		if j.FinishedAt == nil || j.FinishedAt.After(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(s.path(j.ID)); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// writeFileAtomic writes data to dir/name through a temporary file
+func writeFileAtomic(dir, name string, data []byte) error {
+	tmp, err := os.CreateTemp(dir, "."+name+"-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	tmp.Close()
+	return os.Rename(tmp.Name(), filepath.Join(dir, name))
+}
+
+// checkWebhook rejects webhook URLs that are not http or https or whose host
+// is not allowed by --webhook-allow. Entries starting with "*." allow any
+// subdomain.
+func checkWebhook(webhook string) error {
+	u, err := url.Parse(webhook)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("\"webhook\" must be an http or https URL")
+	}
+	if len(serveWebhookAllow) == 0 {
+		return nil
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range serveWebhookAllow {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if host == allowed {
+			return nil
+		}
+		if suffix, ok := strings.CutPrefix(allowed, "*"); ok && strings.HasPrefix(suffix, ".") && strings.HasSuffix(host, suffix) {
+			return nil
+		}
+	}
+	return fmt.Errorf("webhook host %s is not allowed by --webhook-allow", u.Hostname())
+}
+
+// notifyWebhook posts the final state of j to its webhook URL, retrying a
+// few times. It gives up when ctx is done so shutdown does not wait for it.
+func notifyWebhook(ctx context.Context, j *job) error {
+	body, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	var lastErr error
+	for attempt := 1; attempt <= 3; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(2 * time.Second):
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, j.Webhook, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		lastErr = fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return lastErr
+}
+
+// runJobWorker processes queued jobs until the store is closed
+func (s *ocrServer) runJobWorker(ctx context.Context) {
+	for {
+		id, ok := s.jobs.next()
+		if !ok {
+			return
+		}
+		s.runJob(ctx, id)
+	}
+}
+
+func (s *ocrServer) runJob(ctx context.Context, id string) {
+	j, err := s.jobs.load(id)
+	if err != nil {
+		fmt.Printf("Error loading job %s: %v\n", id, err)
+		return
+	}
+
+	if !s.acquire(ctx) {
+		return
+	}
+	started := time.Now().UTC()
+	j.Status = jobRunning
+	j.StartedAt = &started
+	if err := s.jobs.save(j); err != nil {
+		fmt.Printf("Error saving job %s: %v\n", id, err)
+	}
+
+	input := j.URL
+	if input == "" {
+		input = s.jobs.path(id, j.Input)
+	}
+	data, err := ocrDocumentWith(ctx, mistralProvider{client: s.client}, input, ocrOptions{pages: j.Pages, includeImageBase64: includeImageBase64}, discardf)
+	s.release()
+
+	if ctx.Err() != nil {
+		// Interrupted by shutdown; the job is picked up again on the next start
+		j.Status = jobQueued
+		j.StartedAt = nil
+		s.jobs.save(j)
+		return
+	}
+
+	if err == nil {
+		err = writeFileAtomic(s.jobs.path(id), "result.json", data)
+	}
+	if err == nil {
+		var result struct {
+			Pages []json.RawMessage `json:"pages"`
+		}
+		json.Unmarshal(data, &result)
+		j.PageCount = len(result.Pages)
+		j.Status = jobSucceeded
+	} else {
+		j.Status = jobFailed
+		j.Error = err.Error()
+	}
+	finished := time.Now().UTC()
+	j.FinishedAt = &finished
+	if err := s.jobs.save(j); err != nil {
+		fmt.Printf("Error saving job %s: %v\n", id, err)
+	}
+	fmt.Printf("Job %s %s (%s)\n", id, j.Status, j.Source)
+
+	if j.Webhook != "" {
+		if err := notifyWebhook(ctx, j); err != nil {
+			fmt.Printf("Error calling webhook of job %s: %v\n", id, err)
+			j.WebhookError = err.Error()
+			s.jobs.save(j)
+		}
+	}
+}
+
+// jobResponse is a job as reported by the HTTP API
+type jobResponse struct {
+	*job
+	ResultURL string `json:"result_url,omitempty"`
+}
+
+func newJobResponse(j *job) jobResponse {
+	resp := jobResponse{job: j}
+	if j.Status == jobSucceeded {
+		resp.ResultURL = "/v1/jobs/" + j.ID + "/result"
+	}
+	return resp
+}
+
+// handleSubmitJob queues an uploaded document, or a URL given as JSON, and
+// responds with the new job
+func (s *ocrServer) handleSubmitJob(w http.ResponseWriter, r *http.Request) error {
+	id, err := newJobID()
+	if err != nil {
+		return err
+	}
+	query := r.URL.Query()
+	j := &job{
+		ID:        id,
+		Status:    jobQueued,
+		Pages:     query.Get("pages"),
+		Format:    query.Get("format"),
+		Webhook:   query.Get("webhook"),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	dir := s.jobs.path(id)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	// The job is only kept if it was queued
+	queued := false
+	defer func() {
+		if !queued {
+			os.RemoveAll(dir)
+		}
+	}()
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+		path, name, err := receiveUpload(r, func(ext string) (*os.File, error) {
+			return os.Create(filepath.Join(dir, "input"+ext))
+		})
+		if err != nil {
+			return err
+		}
+		j.Source = name
+		j.Input = filepath.Base(path)
+	} else {
+		var body struct {
+			URL     string `json:"url"`
+			Pages   string `json:"pages"`
+			Format  string `json:"format"`
+			Webhook string `json:"webhook"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return badRequest("expected a multipart/form-data upload or a JSON body: %w", err)
+		}
+		if !isURL(body.URL) {
+			return badRequest("\"url\" must be an http or https URL")
+		}
+		j.Source, j.URL = body.URL, body.URL
+		for _, field := range []struct{ value, target *string }{
+			{&body.Pages, &j.Pages}, {&body.Format, &j.Format}, {&body.Webhook, &j.Webhook},
+		} {
+			if *field.value != "" {
+				*field.target = *field.value
+			}
+		}
+	}
+
+	if j.Format, err = requestFormat(j.Format, "json"); err != nil {
+		return err
+	}
+	if _, err := parsePageSelection(j.Pages); err != nil {
+		return badRequest("%v", err)
+	}
+	if j.Webhook != "" {
+		if err := checkWebhook(j.Webhook); err != nil {
+			return badRequest("%v", err)
+		}
+	}
+
+	if err := s.jobs.save(j); err != nil {
+		return err
+	}
+	queued = true
+	s.jobs.enqueue(id)
+
+	w.Header().Set("Location", "/v1/jobs/"+id)
+	writeJSON(w, http.StatusAccepted, newJobResponse(j))
+	return nil
+}
+
+func (s *ocrServer) handleListJobs(w http.ResponseWriter, r *http.Request) error {
+	jobs, err := s.jobs.list()
+	if err != nil {
+		return err
+	}
+
+	resp := make([]jobResponse, len(jobs))
+	for i, j := range jobs {
+		resp[i] = newJobResponse(j)
+	}
+	writeJSON(w, http.StatusOK, resp)
+	return nil
+}
+
+// handleJob serves /v1/jobs/{id} and /v1/jobs/{id}/result
+func (s *ocrServer) handleJob(w http.ResponseWriter, r *http.Request) error {
+	id, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v1/jobs/"), "/")
+	if rest != "" && rest != "result" {
+		return &httpError{status: http.StatusNotFound, err: fmt.Errorf("not found")}
+	}
+
+	j, err := s.jobs.load(id)
+	if os.IsNotExist(err) {
+		return &httpError{status: http.StatusNotFound, err: fmt.Errorf("job %s not found", id)}
+	}
+	if err != nil {
+		return err
+	}
+
+	if rest == "" {
+		writeJSON(w, http.StatusOK, newJobResponse(j))
+		return nil
+	}
+
+	switch j.Status {
+	case jobSucceeded:
+	case jobFailed:
+		return &httpError{status: http.StatusConflict, err: fmt.Errorf("job failed: %s", j.Error)}
+	default:
+		return &httpError{status: http.StatusConflict, err: fmt.Errorf("job is %s", j.Status)}
+	}
+
+	format, err := requestFormat(r.URL.Query().Get("format"), j.Format)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(s.jobs.path(id, "result.json"))
+	if err != nil {
+		return err
+	}
+	result, err := mistral.ParseOCRResult(data)
+	if err != nil {
+		return err
+	}
+	return writeRendered(w, result, data, j.Source, format)
+}
+
+func openJobStoreOrExit() *jobStore {
+	store, err := openJobStore()
+	if err != nil {
+		fmt.Printf("Error opening job directory: %v\n", err)
+		os.Exit(1)
+	}
+	return store
+}
+
+func loadJobOrExit(store *jobStore, id string) *job {
+	j, err := store.load(id)
+	if os.IsNotExist(err) {
+		fmt.Printf("Error: job %s not found\n", id)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	return j
+}
+
+func listJobs() {
+	store := openJobStoreOrExit()
+	jobs, err := store.list()
+	if err != nil {
+		fmt.Printf("Error reading jobs: %v\n", err)
+		os.Exit(1)
+	}
+	if len(jobs) == 0 {
+		fmt.Printf("No jobs in %s\n", store.dir)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS\tCREATED\tPAGES\tSOURCE")
+	for _, j := range jobs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", j.ID, j.Status, j.CreatedAt.Local().Format("2006-01-02 15:04"), j.PageCount, j.Source)
+	}
+	w.Flush()
+}
+
+func showJob(id string) {
+	j := loadJobOrExit(openJobStoreOrExit(), id)
+	data, _ := json.MarshalIndent(j, "", "  ")
+	fmt.Println(string(data))
+}
+
+func jobResult(id string) {
+	store := openJobStoreOrExit()
+	j := loadJobOrExit(store, id)
+	if j.Status != jobSucceeded {
+		fmt.Printf("Error: job %s is %s\n", id, j.Status)
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(store.path(id, "result.json"))
+	if err != nil {
+		fmt.Printf("Error reading result: %v\n", err)
+		os.Exit(1)
+	}
+
+	jsonOutputFile = jobResultOut
+	handleOutput(data)
+}
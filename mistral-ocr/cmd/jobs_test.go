@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCheckWebhook(t *testing.T) {
+	tests := []struct {
+		webhook string
+		allow   []string
+		wantErr bool
+	}{
+		{webhook: "https://hooks.example.com/x"},
+		{webhook: "http://10.0.0.1:9000/hook"},
+		{webhook: "ftp://example.com/x", wantErr: true},
+		{webhook: "file:///etc/passwd", wantErr: true},
+		{webhook: "https://", wantErr: true},
+		{webhook: "https://hooks.example.com/x", allow: []string{"hooks.example.com"}},
+		{webhook: "https://HOOKS.example.com/x", allow: []string{"hooks.example.com"}},
+		{webhook: "https://a.internal:8443/x", allow: []string{"hooks.example.com", "*.internal"}},
+		{webhook: "https://internal/x", allow: []string{"*.internal"}, wantErr: true},
+		{webhook: "http://169.254.169.254/latest", allow: []string{"hooks.example.com"}, wantErr: true},
+		{webhook: "https://hooks.example.com.evil.test/x", allow: []string{"hooks.example.com"}, wantErr: true},
+	}
+
+	defer func() { serveWebhookAllow = nil }()
+	for _, tt := range tests {
+		t.Run(tt.webhook, func(t *testing.T) {
+			serveWebhookAllow = tt.allow
+			if err := checkWebhook(tt.webhook); (err != nil) != tt.wantErr {
+				t.Errorf("checkWebhook(%q) with allow %q = %v, wantErr %v", tt.webhook, tt.allow, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJobStorePrune(t *testing.T) {
+	store := &jobStore{dir: t.TempDir()}
+	now := time.Now().UTC()
+	old, recent := now.Add(-48*time.Hour), now.Add(-time.Hour)
+
+	jobs := map[string]*job{
+		"old finished":    {Status: jobSucceeded, CreatedAt: old, FinishedAt: &old},
+		"old failed":      {Status: jobFailed, CreatedAt: old, FinishedAt: &old},
+		"recent finished": {Status: jobSucceeded, CreatedAt: recent, FinishedAt: &recent},
+		"old queued":      {Status: jobQueued, CreatedAt: old},
+	}
+	ids := make(map[string]string)
+	for name, j := range jobs {
+		id, err := newJobID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.ID = id
+		ids[name] = id
+		if err := os.MkdirAll(store.path(id), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.save(j); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := store.prune(24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("pruned %d jobs, want 2", n)
+	}
+	for name, id := range ids {
+		_, err := store.load(id)
+		if removed := os.IsNotExist(err); removed != (name == "old finished" || name == "old failed") {
+			t.Errorf("job %q removed = %v", name, removed)
+		}
+	}
+}
@@ -0,0 +1,436 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/jsonschema"
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mcpMaxConcurrent int
+
+	mcpCmd = &cobra.Command{
+		Use:   "mcp",
+		Short: "Run a Model Context Protocol server on stdio",
+		Long: `Run a Model Context Protocol (MCP) server over stdin/stdout so LLM agents can OCR
+documents directly. Register it with an MCP client as the command "mistral-ocr mcp".
+
+Tools:
+  ocr_document     OCR a local file or URL and return the OCR JSON
+  ocr_to_markdown  OCR a local file or URL and return Markdown, HTML or plain text
+  extract_fields   extract structured data following a JSON Schema
+
+Relative paths are resolved against the directory the server was started in. Local
+results are cached like those of the process command. Logs are written to stderr.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runMCPServer(cmd.Context(), os.Stdin, os.Stdout)
+		},
+	}
+)
+
+func init() {
+	mcpCmd.Flags().IntVar(&mcpMaxConcurrent, "max-concurrent", 2, "Maximum number of tool calls processed at the same time")
+	mcpCmd.Flags().BoolVar(&includeImages, "images", false, "Include extracted images in rendered output")
+	mcpCmd.Flags().BoolVar(&includePageBreaks, "page-breaks", true, "Include page break indicators between pages")
+	mcpCmd.Flags().BoolVar(&titleFromFilename, "title-from-filename", true, "Use the document name as title")
+}
+
+// mcpProtocolVersions lists the protocol revisions the server speaks, newest first
+var mcpProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// mcpTool describes a tool in tools/list
+type mcpTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+}
+
+// mcpContent is a content item of a tool result
+type mcpContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type mcpToolResult struct {
+	Content []mcpContent `json:"content"`
+	IsError bool         `json:"isError"`
+}
+
+// mcpToolArgs are the arguments accepted by the tools
+type mcpToolArgs struct {
+	Path          string          `json:"path"`
+	Pages         string          `json:"pages"`
+	Format        string          `json:"format"`
+	IncludeImages bool            `json:"include_images"`
+	Schema        json.RawMessage `json:"schema"`
+	Strict        bool            `json:"strict"`
+}
+
+const mcpPathProperty = `"path": {"type": "string", "description": "Local file path or http(s) URL of a PDF or image"},
+    "pages": {"type": "string", "description": "Pages to process, e.g. 1-5,9,12- (default: all)"}`
+
+var mcpTools = []mcpTool{
+	{
+		Name:        "ocr_document",
+		Description: "Run Mistral OCR on a PDF or image and return the raw OCR JSON: the Markdown of every page with image positions and page dimensions.",
+		InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    ` + mcpPathProperty + `,
+    "include_images": {"type": "boolean", "description": "Include extracted images as base64"}
+  },
+  "required": ["path"]
+}`),
+	},
+	{
+		Name:        "ocr_to_markdown",
+		Description: "Run Mistral OCR on a PDF or image and return its text as a single Markdown document, or as HTML or plain text.",
+		InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    ` + mcpPathProperty + `,
+    "format": {"type": "string", "enum": ["markdown", "html", "txt"], "description": "Output format (default: markdown)"}
+  },
+  "required": ["path"]
+}`),
+	},
+	{
+		Name:        "extract_fields",
+		Description: "Extract structured data from a PDF or image. Returns a JSON object following the given JSON Schema, validated against it.",
+		InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    ` + mcpPathProperty + `,
+    "schema": {"type": "object", "description": "JSON Schema describing the fields to extract"},
+    "strict": {"type": "boolean", "description": "Ask the API to follow the schema strictly"}
+  },
+  "required": ["path", "schema"]
+}`),
+	},
+}
+
+// mcpServer answers MCP requests read from stdin
+type mcpServer struct {
+	client *mistral.Client
+	slots  chan struct{}
+
+	// writeMu serializes messages written to out
+	writeMu sync.Mutex
+	out     io.Writer
+
+	// cancels holds the cancel functions of running tool calls by request ID
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+func runMCPServer(ctx context.Context, in io.Reader, out io.Writer) {
+	if mcpMaxConcurrent < 1 {
+		fmt.Fprintln(os.Stderr, "Error: --max-concurrent must be at least 1")
+		os.Exit(1)
+	}
+
+	s := &mcpServer{
+		client:  newClient(),
+		slots:   make(chan struct{}, mcpMaxConcurrent),
+		out:     out,
+		cancels: make(map[string]context.CancelFunc),
+	}
+
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		reader := bufio.NewReader(in)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(bytes.TrimSpace(line)) > 0 {
+				lines <- line
+			}
+			if err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	var calls sync.WaitGroup
+	for {
+		select {
+		case <-ctx.Done():
+			// Running calls see the cancelled context and are not waited for
+			return
+		case err := <-readErr:
+			if err != io.EOF {
+				fmt.Fprintf(os.Stderr, "Error reading stdin: %v\n", err)
+			}
+			calls.Wait()
+			return
+		case line := <-lines:
+			s.handleMessage(ctx, line, &calls)
+		}
+	}
+}
+
+// handleMessage dispatches a JSON-RPC message. Tool calls run in the
+// background so that further requests, e.g. cancellations, are still read.
+func (s *mcpServer) handleMessage(ctx context.Context, line []byte, calls *sync.WaitGroup) {
+	var req rpcRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		s.respond(nil, nil, &rpcError{Code: rpcParseError, Message: fmt.Sprintf("parse error: %v", err)})
+		return
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		s.respond(req.ID, nil, &rpcError{Code: rpcInvalidRequest, Message: "invalid JSON-RPC 2.0 request"})
+		return
+	}
+
+	// Notifications have no ID and get no response
+	if req.ID == nil {
+		if req.Method == "notifications/cancelled" {
+			s.cancelCall(req.Params)
+		}
+		return
+	}
+
+	switch req.Method {
+	case "initialize":
+		s.respond(req.ID, s.initialize(req.Params), nil)
+	case "ping":
+		s.respond(req.ID, struct{}{}, nil)
+	case "tools/list":
+		s.respond(req.ID, map[string]interface{}{"tools": mcpTools}, nil)
+	case "tools/call":
+		callCtx, cancel := context.WithCancel(ctx)
+		s.mu.Lock()
+		s.cancels[string(req.ID)] = cancel
+		s.mu.Unlock()
+
+		calls.Add(1)
+		go func() {
+			defer calls.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.cancels, string(req.ID))
+				s.mu.Unlock()
+				cancel()
+			}()
+
+			result, err := s.callTool(callCtx, req.Params)
+			// Cancelled requests must not be answered
+			if callCtx.Err() != nil {
+				return
+			}
+			if err != nil {
+				s.respond(req.ID, nil, err.(*rpcError))
+				return
+			}
+			s.respond(req.ID, result, nil)
+		}()
+	default:
+		s.respond(req.ID, nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("method not found: %s", req.Method)})
+	}
+}
+
+// initialize agrees on the protocol version and describes the server
+func (s *mcpServer) initialize(params json.RawMessage) interface{} {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	json.Unmarshal(params, &p)
+
+	version := mcpProtocolVersions[0]
+	for _, v := range mcpProtocolVersions {
+		if v == p.ProtocolVersion {
+			version = v
+		}
+	}
+
+	return map[string]interface{}{
+		"protocolVersion": version,
+		"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+		"serverInfo":      map[string]string{"name": "mistral-ocr", "version": Version},
+	}
+}
+
+func (s *mcpServer) cancelCall(params json.RawMessage) {
+	var p struct {
+		RequestID json.RawMessage `json:"requestId"`
+	}
+	if json.Unmarshal(params, &p) != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel, ok := s.cancels[string(p.RequestID)]; ok {
+		cancel()
+	}
+}
+
+func (s *mcpServer) respond(id json.RawMessage, result interface{}, rpcErr *rpcError) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	data, err := json.Marshal(rpcResponse{JSONRPC: "2.0", ID: id, Result: result, Error: rpcErr})
+	if err != nil {
+		data, _ = json.Marshal(rpcResponse{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: -32603, Message: err.Error()}})
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.out.Write(append(data, '\n'))
+}
+
+// callTool runs a tools/call request. Problems with the request are returned
+// as JSON-RPC errors, failures of the tool itself as error results.
+func (s *mcpServer) callTool(ctx context.Context, params json.RawMessage) (*mcpToolResult, error) {
+	var p struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("invalid params: %v", err)}
+	}
+
+	var args mcpToolArgs
+	if len(p.Arguments) > 0 {
+		if err := json.Unmarshal(p.Arguments, &args); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("invalid arguments: %v", err)}
+		}
+	}
+	if args.Path == "" {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "argument \"path\" is required"}
+	}
+
+	var run func(context.Context, mcpToolArgs) (string, error)
+	switch p.Name {
+	case "ocr_document":
+		run = s.ocrDocumentTool
+	case "ocr_to_markdown":
+		run = s.ocrToMarkdownTool
+	case "extract_fields":
+		run = s.extractFieldsTool
+	default:
+		return nil, &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("unknown tool: %s", p.Name)}
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, &rpcError{Code: rpcInvalidRequest, Message: ctx.Err().Error()}
+	}
+	defer func() { <-s.slots }()
+
+	fmt.Fprintf(os.Stderr, "%s %s\n", p.Name, args.Path)
+	text, err := run(ctx, args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s %s failed: %v\n", p.Name, args.Path, err)
+		return &mcpToolResult{Content: []mcpContent{{Type: "text", Text: "Error: " + err.Error()}}, IsError: true}, nil
+	}
+	return &mcpToolResult{Content: []mcpContent{{Type: "text", Text: text}}}, nil
+}
+
+// ocr runs OCR on the document of args
+func (s *mcpServer) ocr(ctx context.Context, args mcpToolArgs, opts ocrOptions) (*mistral.OCRResult, []byte, error) {
+	opts.pages = args.Pages
+	data, err := ocrDocumentWith(ctx, mistralProvider{client: s.client}, args.Path, opts, discardf)
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := mistral.ParseOCRResult(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, data, nil
+}
+
+func (s *mcpServer) ocrDocumentTool(ctx context.Context, args mcpToolArgs) (string, error) {
+	_, data, err := s.ocr(ctx, args, ocrOptions{includeImageBase64: args.IncludeImages})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (s *mcpServer) ocrToMarkdownTool(ctx context.Context, args mcpToolArgs) (string, error) {
+	if args.Format == "" {
+		args.Format = "markdown"
+	}
+	format, ok := outputFormats[args.Format]
+	if !ok || args.Format == "jsonl" {
+		return "", fmt.Errorf("unsupported format '%s' (expected markdown, html or txt)", args.Format)
+	}
+
+	result, _, err := s.ocr(ctx, args, ocrOptions{includeImageBase64: includeImages})
+	if err != nil {
+		return "", err
+	}
+	name := args.Path
+	if !isURL(name) {
+		name = filepath.Base(name)
+	}
+	return format.render(result, name, "", "")
+}
+
+func (s *mcpServer) extractFieldsTool(ctx context.Context, args mcpToolArgs) (string, error) {
+	if len(args.Schema) == 0 {
+		return "", fmt.Errorf("argument \"schema\" is required")
+	}
+	schema, err := jsonschema.Compile(args.Schema)
+	if err != nil {
+		return "", err
+	}
+
+	opts := ocrOptions{documentAnnotation: mistral.NewJSONSchemaFormat("fields", args.Schema, args.Strict)}
+	result, _, err := s.ocr(ctx, args, opts)
+	if err != nil {
+		return "", err
+	}
+
+	document, err := parseAnnotation(result.DocumentAnnotation, schema)
+	if err != nil {
+		return "", err
+	}
+	return string(document), nil
+}
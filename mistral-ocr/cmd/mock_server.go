@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral/mistraltest"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mockAddr     string
+	mockKey      string
+	mockResponse string
+	mockFailures []string
+	mockLatency  time.Duration
+
+	mockServerCmd = &cobra.Command{
+		Use:   "mock-server",
+		Short: "Run a mock Mistral API server for offline testing",
+		Long: `Run a local server emulating the Mistral /files, /files/{id}, /files/{id}/url and /ocr endpoints,
+so the CLI can be exercised without an API key or network access:
+
+  mistral-ocr mock-server --addr 127.0.0.1:8765 &
+  MISTRAL_API_KEY=test MISTRAL_BASE_URL=http://127.0.0.1:8765/v1 mistral-ocr process doc.pdf
+
+OCR responses contain a placeholder page for every page of the document, or the OCR JSON
+given with --response. Failures are injected with --fail endpoint=status[:times], where
+endpoint is files, files-list, file, file-delete, file-url or ocr and status an HTTP status code or "empty" for a 200
+response without body. Without a count the failure applies to every request:
+
+  --fail ocr=429:2 --fail files=empty:1 --fail file-url=500`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runMockServer(cmd.Context())
+		},
+	}
+)
+
+func init() {
+	mockServerCmd.Flags().StringVar(&mockAddr, "addr", "127.0.0.1:8765", "Address to listen on")
+	mockServerCmd.Flags().StringVar(&mockKey, "require-key", "", "Only accept this API key (default: any key)")
+	mockServerCmd.Flags().StringVar(&mockResponse, "response", "", "OCR JSON file returned for every OCR request")
+	mockServerCmd.Flags().StringArrayVar(&mockFailures, "fail", nil, "Inject a failure, e.g. ocr=429:2 or files=empty (repeatable)")
+	mockServerCmd.Flags().DurationVar(&mockLatency, "latency", 0, "Delay every response by this duration")
+}
+
+func runMockServer(ctx context.Context) {
+	mock := mistraltest.NewServer()
+	mock.APIKey = mockKey
+	mock.Latency = mockLatency
+
+	if mockResponse != "" {
+		result, err := readOCRResult(mockResponse)
+		if err != nil {
+			fmt.Printf("Error reading response file: %v\n", err)
+			os.Exit(1)
+		}
+		mock.OCR = mistraltest.Canned(result)
+	}
+
+	for _, spec := range mockFailures {
+		failure, err := mistraltest.ParseFailure(spec)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		mock.Fail(failure)
+	}
+
+	listener, err := net.Listen("tcp", mockAddr)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	srv := &http.Server{
+		Handler: logRequests(mock),
+		BaseContext: func(net.Listener) context.Context {
+			return context.Background()
+		},
+	}
+
+	fmt.Printf("Mock Mistral API listening on http://%s (base URL http://%s/v1)\n", listener.Addr(), listener.Addr())
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.Serve(listener)
+	}()
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}
+}
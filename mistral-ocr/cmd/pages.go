@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/pdf"
+)
+
+// pageSpec holds the --pages flag shared by process, markdown and convert
+var pageSpec string
+
+// pageRange is an inclusive range of zero-based page indices. An end of -1
+// means the range runs to the last page of the document.
+type pageRange struct {
+	start int
+	end   int
+}
+
+// pageSelection is a parsed page list such as "1-5,9,12-". An empty
+// selection selects every page.
+type pageSelection []pageRange
+
+// parsePageSelection parses a comma separated list of one-based pages and
+// ranges. Ranges may omit their start ("-3") or end ("12-").
+func parsePageSelection(spec string) (pageSelection, error) {
+	var sel pageSelection
+	if strings.TrimSpace(spec) == "" {
+		return sel, nil
+	}
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		from, to, isRange := strings.Cut(part, "-")
+		r := pageRange{start: 0, end: -1}
+		if isRange && strings.TrimSpace(from) == "" && strings.TrimSpace(to) == "" {
+			return nil, fmt.Errorf("invalid page range '%s' in --pages", part)
+		}
+
+		if from = strings.TrimSpace(from); from != "" {
+			n, err := strconv.Atoi(from)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid page '%s' in --pages", from)
+			}
+			r.start = n - 1
+		} else if !isRange {
+			return nil, fmt.Errorf("invalid page range '%s' in --pages", part)
+		}
+
+		if !isRange {
+			r.end = r.start
+		} else if to = strings.TrimSpace(to); to != "" {
+			n, err := strconv.Atoi(to)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid page '%s' in --pages", to)
+			}
+			r.end = n - 1
+			if r.end < r.start {
+				return nil, fmt.Errorf("invalid page range '%s' in --pages: end before start", part)
+			}
+		}
+
+		sel = append(sel, r)
+	}
+	return sel, nil
+}
+
+// includes reports whether the zero-based page index is selected
+func (s pageSelection) includes(index int) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for _, r := range s {
+		if index >= r.start && (r.end < 0 || index <= r.end) {
+			return true
+		}
+	}
+	return false
+}
+
+// openEnded reports whether the selection runs to the last page, which
+// requires the page count to list its indices
+func (s pageSelection) openEnded() bool {
+	for _, r := range s {
+		if r.end < 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// indices lists the selected zero-based page indices in ascending order.
+// Open ended ranges are cut at total pages; a negative total means unknown.
+func (s pageSelection) indices(total int) []int {
+	last := total - 1
+	if total < 0 {
+		last = 0
+		for _, r := range s {
+			if r.start > last {
+				last = r.start
+			}
+			if r.end > last {
+				last = r.end
+			}
+		}
+	}
+
+	var indices []int
+	for i := 0; i <= last; i++ {
+		if s.includes(i) {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// requestPages returns the page indices to send to the OCR endpoint, or nil
+// if every page has to be requested. Images have no pages to select and open
+// ended selections can only be sent for local PDFs, whose page count is known.
+func requestPages(sel pageSelection, fileOrURL string) []int {
+	name := fileOrURL
+	var stdinData []byte
+	if fileOrURL == stdinArg {
+		var err error
+		if stdinData, name, err = readStdin(); err != nil {
+			return nil
+		}
+	}
+
+	if len(sel) == 0 || mistral.DocumentTypeFor(name) != mistral.DocumentURL {
+		return nil
+	}
+	if !sel.openEnded() {
+		return sel.indices(-1)
+	}
+	if isURL(fileOrURL) {
+		return nil
+	}
+
+	var doc *pdf.Reader
+	var err error
+	if stdinData != nil {
+		doc, err = pdf.NewReader(stdinData)
+	} else {
+		doc, err = pdf.Open(fileOrURL)
+	}
+	if err != nil {
+		return nil
+	}
+	return sel.indices(doc.NumPages())
+}
+
+// filterPages drops the pages of result that are not selected
+func filterPages(result *mistral.OCRResult, sel pageSelection) {
+	if len(sel) == 0 {
+		return
+	}
+
+	pages := result.Pages[:0]
+	for _, page := range result.Pages {
+		if sel.includes(page.Index) {
+			pages = append(pages, page)
+		}
+	}
+	result.Pages = pages
+}
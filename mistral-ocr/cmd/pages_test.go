@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+)
+
+func TestParsePageSelection(t *testing.T) {
+	tests := []struct {
+		spec    string
+		total   int
+		want    []int
+		wantErr bool
+	}{
+		{spec: "", total: 3, want: []int{0, 1, 2}},
+		{spec: "2", total: 5, want: []int{1}},
+		{spec: "1-3", total: 5, want: []int{0, 1, 2}},
+		{spec: "1-3,5", total: 6, want: []int{0, 1, 2, 4}},
+		{spec: "4-", total: 6, want: []int{3, 4, 5}},
+		{spec: "-2", total: 6, want: []int{0, 1}},
+		{spec: " 2 , 4 ", total: 6, want: []int{1, 3}},
+		// Duplicates and overlapping ranges select each page once, in order
+		{spec: "3,1-3,2", total: 6, want: []int{0, 1, 2}},
+		// Pages past the end of the document are ignored
+		{spec: "2,9-12", total: 4, want: []int{1}},
+		{spec: "9-", total: 4, want: nil},
+		{spec: "0", wantErr: true},
+		{spec: "0-3", wantErr: true},
+		{spec: "3-0", wantErr: true},
+		{spec: "5-2", wantErr: true},
+		{spec: "x", wantErr: true},
+		{spec: "1-y", wantErr: true},
+		{spec: "-1-", wantErr: true},
+		{spec: "-", wantErr: true},
+		{spec: " - ", wantErr: true},
+		{spec: "1,-", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			sel, err := parsePageSelection(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", sel)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []int
+			for i := 0; i < tt.total; i++ {
+				if sel.includes(i) {
+					got = append(got, i)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("includes selects %v, want %v", got, tt.want)
+			}
+			if len(sel) > 0 {
+				if got := sel.indices(tt.total); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("indices(%d) = %v, want %v", tt.total, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPageSelectionIndicesUnknownTotal(t *testing.T) {
+	sel, err := parsePageSelection("3,1-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sel.openEnded() {
+		t.Error("closed selection reported as open ended")
+	}
+	if got, want := sel.indices(-1), []int{0, 1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("indices(-1) = %v, want %v", got, want)
+	}
+
+	open, err := parsePageSelection("2-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !open.openEnded() {
+		t.Error("open selection not reported as open ended")
+	}
+}
+
+func TestFilterPages(t *testing.T) {
+	sel, err := parsePageSelection("2,4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := &mistral.OCRResult{Pages: []mistral.Page{{Index: 0}, {Index: 1}, {Index: 2}, {Index: 3}}}
+	filterPages(result, sel)
+
+	var got []int
+	for _, p := range result.Pages {
+		got = append(got, p.Index)
+	}
+	if want := []int{1, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("kept pages %v, want %v", got, want)
+	}
+}
@@ -2,36 +2,37 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/tesseract"
 	"github.com/spf13/cobra"
 )
 
 var (
 	jsonOutputFile     string
 	includeImageBase64 bool
+	keepUpload         bool
+
+	// Annotation formats requested by the extract command
+	documentAnnotationFormat *mistral.ResponseFormat
+	bboxAnnotationFormat     *mistral.ResponseFormat
 
 	processCmd = &cobra.Command{
 		Use:   "process [file]",
 		Short: "Process a document with OCR",
 		Long: `Process a document file (PDF, image) using Mistral AI's OCR capabilities.
-The file can be a local file or a URL.`,
+The file can be a local file, a URL or - to read the document from stdin.`,
 		Args: cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			filePath := args[0]
-
-			// Determine if input is a URL or a local file
-			if strings.HasPrefix(filePath, "http://") || strings.HasPrefix(filePath, "https://") {
-				processURL(filePath)
-			} else {
-				// add debug logging of filePath
-				processLocalFile(filePath)
-			}
+			processDocument(cmd.Context(), args[0])
 		},
 	}
 )
@@ -39,86 +40,246 @@ The file can be a local file or a URL.`,
 func init() {
 	processCmd.Flags().StringVarP(&jsonOutputFile, "output-file", "o", "", "Output JSON file path (default is stdout)")
 	processCmd.Flags().BoolVar(&includeImageBase64, "include-images", false, "Include base64 encoded images in the output")
+	processCmd.Flags().StringVar(&pageSpec, "pages", "", "Only process these pages, e.g. 1-5,9,12-")
+	processCmd.Flags().BoolVar(&keepUpload, "keep-upload", false, "Keep the uploaded file in your Mistral account instead of deleting it after OCR")
+	processCmd.Flags().StringVar(&recordDir, "record", "", "Save every API request and response to this directory (API key redacted)")
+	processCmd.Flags().StringVar(&replayDir, "replay", "", "Answer API requests from a directory written by --record instead of the network")
+	processCmd.Flags().StringVar(&providerName, "provider", "", "OCR provider: mistral or tesseract (defaults to MISTRAL_OCR_PROVIDER env variable or mistral)")
+	processCmd.Flags().StringVar(&tesseractLang, "tesseract-lang", "", "Tesseract languages, e.g. eng+deu (defaults to MISTRAL_OCR_TESSERACT_LANG env variable)")
+	processCmd.Flags().IntVar(&tesseractDPI, "tesseract-dpi", tesseract.DefaultDPI, "Resolution PDF pages are rasterized at for tesseract")
 }
 
-func processURL(url string) {
-	// Create Mistral client
-	client := mistral.NewClient(getAPIKey())
-	if client == nil {
-		fmt.Println("Error: MISTRAL_API_KEY environment variable is not set and no --api-key flag was provided")
-		os.Exit(1)
+// isURL reports whether fileOrURL points at a remote document instead of a local file
+func isURL(fileOrURL string) bool {
+	return strings.HasPrefix(fileOrURL, "http://") || strings.HasPrefix(fileOrURL, "https://")
+}
+
+// progressf prints progress messages of single document commands
+func progressf(format string, a ...interface{}) {
+	fmt.Printf(format, a...)
+}
+
+// discardf drops progress messages, e.g. for concurrent batch workers
+func discardf(format string, a ...interface{}) {}
+
+// ocrOptions are the settings of a single OCR run
+type ocrOptions struct {
+	pages              string
+	includeImageBase64 bool
+	documentAnnotation *mistral.ResponseFormat
+	bboxAnnotation     *mistral.ResponseFormat
+	// stdin reads the document from stdin if the input is "-". Only commands
+	// that own stdin enable it; MCP for one speaks JSON-RPC over it.
+	stdin bool
+}
+
+// flagOCROptions returns the OCR settings given on the command line
+func flagOCROptions() ocrOptions {
+	return ocrOptions{
+		pages:              pageSpec,
+		includeImageBase64: includeImageBase64,
+		documentAnnotation: documentAnnotationFormat,
+		bboxAnnotation:     bboxAnnotationFormat,
+	}
+}
+
+// ocrDocument runs OCR on a local file or URL with the command line settings
+// and returns the raw JSON response. Local files are uploaded first and
+// processed through their signed URL.
+func ocrDocument(ctx context.Context, provider OCRProvider, fileOrURL string, logf func(string, ...interface{})) ([]byte, error) {
+	return ocrDocumentWith(ctx, provider, fileOrURL, flagOCROptions(), logf)
+}
+
+// ocrDocumentWith is like ocrDocument with explicit settings
+func ocrDocumentWith(ctx context.Context, provider OCRProvider, fileOrURL string, opts ocrOptions, logf func(string, ...interface{})) ([]byte, error) {
+	sel, err := parsePageSelection(opts.pages)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if file exists
+	if fileOrURL == stdinArg {
+		if !opts.stdin {
+			return nil, fmt.Errorf("reading the document from stdin is only supported by process and markdown (use ./- for a file named -)")
+		}
+		if _, _, err := readStdin(); err != nil {
+			return nil, err
+		}
+	} else if !isURL(fileOrURL) {
+		if _, err := os.Stat(fileOrURL); os.IsNotExist(err) {
+			return nil, fmt.Errorf("file '%s' does not exist", fileOrURL)
+		}
 	}
 
-	// Determine the document type based on URL
-	docType := "document_url"
-	if strings.HasSuffix(strings.ToLower(url), ".jpg") ||
-		strings.HasSuffix(strings.ToLower(url), ".jpeg") ||
-		strings.HasSuffix(strings.ToLower(url), ".png") ||
-		strings.HasSuffix(strings.ToLower(url), ".webp") ||
-		strings.HasSuffix(strings.ToLower(url), ".gif") {
-		docType = "image_url"
+	req := &mistral.OCRRequest{
+		IncludeImageBase64:       opts.includeImageBase64,
+		Pages:                    requestPages(sel, fileOrURL),
+		DocumentAnnotationFormat: opts.documentAnnotation,
+		BBoxAnnotationFormat:     opts.bboxAnnotation,
 	}
 
-	// Process the document
-	respData, err := client.ProcessOCR(docType, url, includeImageBase64)
+	respData, err := ocrRequest(ctx, provider, fileOrURL, req, logf)
 	if err != nil {
-		fmt.Printf("Error processing document: %v\n", err)
-		os.Exit(1)
+		return nil, err
 	}
 
-	// Handle the output
-	handleOutput(respData)
+	// Selections the API could not be given are applied to the response instead
+	if len(sel) > 0 && req.Pages == nil {
+		result, err := mistral.ParseOCRResult(respData)
+		if err != nil {
+			return nil, err
+		}
+		filterPages(result, sel)
+		return json.Marshal(result)
+	}
+
+	return respData, nil
 }
 
-func processLocalFile(filePath string) {
-	fmt.Printf("Processing local file: %s\n", filePath)
-	// Check if file exists
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		fmt.Printf("Error: file '%s' does not exist\n", filePath)
-		os.Exit(1)
+// ocrRequest runs req against a URL, stdin or a local file, consulting the
+// result cache for stdin and local files
+func ocrRequest(ctx context.Context, provider OCRProvider, fileOrURL string, req *mistral.OCRRequest, logf func(string, ...interface{})) ([]byte, error) {
+	if isURL(fileOrURL) {
+		return provider.OCRURL(ctx, fileOrURL, req)
 	}
 
-	// Create Mistral client
-	client := mistral.NewClient(getAPIKey())
-	if client == nil {
-		fmt.Println("Error: MISTRAL_API_KEY environment variable is not set and no --api-key flag was provided")
-		os.Exit(1)
+	var stdinData []byte
+	var stdinName string
+	if fileOrURL == stdinArg {
+		var err error
+		if stdinData, stdinName, err = readStdin(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Reuse a cached result for identical content and options
+	var sum, key string
+	if !noCache {
+		var err error
+		if stdinData != nil {
+			sum = dataSHA256(stdinData)
+		} else if sum, err = fileSHA256(fileOrURL); err != nil {
+			return nil, fmt.Errorf("error hashing file: %v", err)
+		}
+		key = cacheKey(sum, provider.Model(), *req)
+		if cached, ok := loadCachedResult(key); ok {
+			logf("Using cached OCR result (key %s)\n", key[:12])
+			return cached, nil
+		}
 	}
 
+	var respData []byte
+	var err error
+	if stdinData != nil {
+		respData, err = provider.OCRReader(ctx, bytes.NewReader(stdinData), stdinName, int64(len(stdinData)), req, logf)
+	} else {
+		respData, err = provider.OCRFile(ctx, fileOrURL, req, logf)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !noCache {
+		if err := storeCachedResult(key, fileOrURL, sum, provider.Model(), respData); err != nil {
+			logf("Warning: could not cache OCR result: %v\n", err)
+		}
+	}
+
+	return respData, nil
+}
+
+// ocrLocalFile runs OCR on a local file
+func ocrLocalFile(ctx context.Context, client *mistral.Client, filePath string, req *mistral.OCRRequest, logf func(string, ...interface{})) ([]byte, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	return ocrReader(ctx, client, f, filepath.Base(filePath), info.Size(), req, logf)
+}
+
+// ocrReader runs OCR on the document read from r. name is the file name the
+// document type is derived from and size its length. PDFs above the upload
+// size limit are split into smaller documents whose results are merged back
+// together.
+func ocrReader(ctx context.Context, client *mistral.Client, r io.Reader, name string, size int64, req *mistral.OCRRequest, logf func(string, ...interface{})) ([]byte, error) {
+	if size > mistral.MaxFileSize && strings.EqualFold(filepath.Ext(name), ".pdf") {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("error reading PDF for splitting: %v", err)
+		}
+		return ocrSplitPDF(ctx, client, data, name, req, logf)
+	}
+
+	return ocrUploadedReader(ctx, client, r, name, size, req, logf)
+}
+
+// ocrUploadedReader uploads the document read from r and runs OCR through its
+// signed URL. The upload is deleted afterwards unless --keep-upload is set.
+func ocrUploadedReader(ctx context.Context, client *mistral.Client, r io.Reader, name string, size int64, req *mistral.OCRRequest, logf func(string, ...interface{})) ([]byte, error) {
 	// Upload the file to Mistral API
-	fileID, err := client.UploadFile(filePath)
+	fileID, err := client.UploadReaderContext(ctx, r, name, size)
 	if err != nil {
-		fmt.Printf("Error uploading file: %v\n", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("error uploading file: %w", err)
 	}
 
-	fmt.Printf("File uploaded successfully with ID: %s\n", fileID)
+	logf("File uploaded successfully with ID: %s\n", fileID)
+	if !keepUpload {
+		defer deleteUpload(ctx, client, fileID, logf)
+	}
 
 	// Get the signed file URL for processing
-	fileURL, err := client.GetFileURL(fileID)
+	fileURL, err := client.GetFileURLContext(ctx, fileID)
 	if err != nil {
-		fmt.Printf("Error getting signed file URL: %v\n", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("error getting signed file URL: %w", err)
 	}
 
 	// Determine the document type based on file extension
-	docType := "document_url"
-	lowerFilePath := strings.ToLower(filePath)
-	if strings.HasSuffix(lowerFilePath, ".jpg") ||
-		strings.HasSuffix(lowerFilePath, ".jpeg") ||
-		strings.HasSuffix(lowerFilePath, ".png") ||
-		strings.HasSuffix(lowerFilePath, ".webp") ||
-		strings.HasSuffix(lowerFilePath, ".gif") {
-		docType = "image_url"
-	}
+	docType := mistral.DocumentTypeFor(name)
 
-	fmt.Printf("Processing with signed file URL (type: %s)\n", docType)
+	logf("Processing with signed file URL (type: %s)\n", docType)
 
 	// Process the uploaded file with the appropriate type
-	respData, err := client.ProcessOCR(docType, fileURL, includeImageBase64)
+	fileReq := *req
+	fileReq.Document = mistral.NewDocument(docType, fileURL)
+	return client.OCRRaw(ctx, &fileReq)
+}
+
+// deleteUpload deletes an uploaded file once OCR is done with it. It also runs
+// after an interrupt, so it does not use the cancellation of ctx.
+func deleteUpload(ctx context.Context, client *mistral.Client, fileID string, logf func(string, ...interface{})) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+
+	if err := client.DeleteFile(ctx, fileID); err != nil {
+		logf("Warning: could not delete uploaded file %s: %v\n", fileID, err)
+		return
+	}
+	logf("Deleted uploaded file %s\n", fileID)
+}
+
+func processDocument(ctx context.Context, fileOrURL string) {
+	provider := newProvider()
+
+	if fileOrURL == stdinArg {
+		fmt.Println("Processing document from stdin")
+	} else if !isURL(fileOrURL) {
+		fmt.Printf("Processing local file: %s\n", fileOrURL)
+	}
+
+	opts := flagOCROptions()
+	opts.stdin = true
+	respData, err := ocrDocumentWith(ctx, provider, fileOrURL, opts, progressf)
 	if err != nil {
+		exitIfInterrupted(ctx)
 		fmt.Printf("Error processing document: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 
 	// Handle the output
@@ -145,7 +306,7 @@ func handleOutput(data []byte) {
 		}
 
 		// Write the file
-		if err := os.WriteFile(jsonOutputFile, prettyJSON.Bytes(), 0644); err != nil {
+		if err := writeOutputFile(jsonOutputFile, prettyJSON.Bytes()); err != nil {
 			fmt.Printf("Error writing output file: %v\n", err)
 			os.Exit(1)
 		}
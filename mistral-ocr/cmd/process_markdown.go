@@ -1,11 +1,11 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"strings"
 
-	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/tesseract"
 	"github.com/spf13/cobra"
 )
 
@@ -14,11 +14,12 @@ var (
 		Use:   "markdown [file_or_url]",
 		Short: "Process document and convert to markdown in one step",
 		Long: `Process a document with OCR and convert the output directly to markdown.
-This combines the 'process' and 'convert' commands in a single operation.`,
+This combines the 'process' and 'convert' commands in a single operation.
+Use - as file to read the document from stdin.`,
 		Args: cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			fileOrURL := args[0]
-			processAndConvertToMarkdown(fileOrURL)
+			processAndConvertToMarkdown(cmd.Context(), fileOrURL)
 		},
 	}
 
@@ -36,10 +37,18 @@ func init() {
 	processMarkdownCmd.Flags().BoolVar(&includePageBreaks, "page-breaks", true, "Include page break indicators between pages")
 	processMarkdownCmd.Flags().BoolVar(&titleFromFilename, "title-from-filename", true, "Use filename as document title")
 	processMarkdownCmd.Flags().BoolVar(&singleFile, "single-file", false, "Create a single markdown file instead of one per page")
-
-	// Ensure that if --images is true, includeImageBase64 is also true
+	processMarkdownCmd.Flags().StringVar(&pageSpec, "pages", "", "Only process these pages, e.g. 1-5,9,12-")
+	processMarkdownCmd.Flags().BoolVar(&keepUpload, "keep-upload", false, "Keep the uploaded file in your Mistral account instead of deleting it after OCR")
+	processMarkdownCmd.Flags().StringVar(&imagesDir, "images-dir", "", "Write images to this directory (relative to --output-dir) and link them instead of inlining")
+	processMarkdownCmd.Flags().StringVar(&recordDir, "record", "", "Save every API request and response to this directory (API key redacted)")
+	processMarkdownCmd.Flags().StringVar(&replayDir, "replay", "", "Answer API requests from a directory written by --record instead of the network")
+	processMarkdownCmd.Flags().StringVar(&providerName, "provider", "", "OCR provider: mistral or tesseract (defaults to MISTRAL_OCR_PROVIDER env variable or mistral)")
+	processMarkdownCmd.Flags().StringVar(&tesseractLang, "tesseract-lang", "", "Tesseract languages, e.g. eng+deu (defaults to MISTRAL_OCR_TESSERACT_LANG env variable)")
+	processMarkdownCmd.Flags().IntVar(&tesseractDPI, "tesseract-dpi", tesseract.DefaultDPI, "Resolution PDF pages are rasterized at for tesseract")
+
+	// Ensure that if --images or --images-dir is set, includeImageBase64 is also true
 	processMarkdownCmd.PreRun = func(cmd *cobra.Command, args []string) {
-		if includeImages {
+		if includeImages || imagesDir != "" {
 			includeImageBase64 = true
 		}
 
@@ -50,7 +59,7 @@ func init() {
 	}
 }
 
-func processAndConvertToMarkdown(fileOrURL string) {
+func processAndConvertToMarkdown(ctx context.Context, fileOrURL string) {
 	// Create temporary file for JSON output if not specified
 	var jsonOutputPath string
 	if jsonOutputFile == "" {
@@ -61,84 +70,30 @@ func processAndConvertToMarkdown(fileOrURL string) {
 		}
 		defer os.Remove(tmpFile.Name()) // Clean up temporary file when done
 		tmpFile.Close()
+		partialOutputs.track(tmpFile.Name())
 		jsonOutputPath = tmpFile.Name()
 	} else {
 		jsonOutputPath = jsonOutputFile
 	}
 
-	// Step 1: Process the document - reuse logic from processURL and processLocalFile
-	var respData []byte
-	var err error
-
-	// Create Mistral client
-	client := mistral.NewClient(getAPIKey())
-	if client == nil {
-		fmt.Println("Error: MISTRAL_API_KEY environment variable is not set and no --api-key flag was provided")
-		os.Exit(1)
-	}
-
-	// Determine if input is URL or local file
-	if strings.HasPrefix(fileOrURL, "http://") || strings.HasPrefix(fileOrURL, "https://") {
-		// Process URL
-		docType := "document_url"
-		if strings.HasSuffix(strings.ToLower(fileOrURL), ".jpg") ||
-			strings.HasSuffix(strings.ToLower(fileOrURL), ".jpeg") ||
-			strings.HasSuffix(strings.ToLower(fileOrURL), ".png") ||
-			strings.HasSuffix(strings.ToLower(fileOrURL), ".webp") ||
-			strings.HasSuffix(strings.ToLower(fileOrURL), ".gif") {
-			docType = "image_url"
-		}
+	// Step 1: Process the document
+	provider := newProvider()
 
+	if isURL(fileOrURL) {
 		fmt.Printf("Processing URL: %s\n", fileOrURL)
-		respData, err = client.ProcessOCR(docType, fileOrURL, includeImageBase64)
+	} else if fileOrURL == stdinArg {
+		fmt.Println("Processing document from stdin")
 	} else {
-		// Process local file
-		if _, err := os.Stat(fileOrURL); os.IsNotExist(err) {
-			fmt.Printf("Error: file '%s' does not exist\n", fileOrURL)
-			os.Exit(1)
-		}
-
 		fmt.Printf("Processing local file: %s\n", fileOrURL)
-		fileID, err := client.UploadFile(fileOrURL)
-		if err != nil {
-			fmt.Printf("Error uploading file: %v\n", err)
-			os.Exit(1)
-		}
-
-		fmt.Printf("File uploaded successfully with ID: %s\n", fileID)
-
-		// Get the signed file URL for processing
-		fileURL, err := client.GetFileURL(fileID)
-		if err != nil {
-			fmt.Printf("Error getting signed file URL: %v\n", err)
-			os.Exit(1)
-		}
-
-		// Determine the document type based on file extension
-		docType := "document_url"
-		lowerFilePath := strings.ToLower(fileOrURL)
-		if strings.HasSuffix(lowerFilePath, ".jpg") ||
-			strings.HasSuffix(lowerFilePath, ".jpeg") ||
-			strings.HasSuffix(lowerFilePath, ".png") ||
-			strings.HasSuffix(lowerFilePath, ".webp") ||
-			strings.HasSuffix(lowerFilePath, ".gif") {
-			docType = "image_url"
-		}
-
-		fmt.Printf("Processing with signed file URL (type: %s)\n", docType)
-		fmt.Printf("File URL: %s\n", fileURL)
-		fmt.Printf("Include Image Base64: %v\n", includeImageBase64)
-		respData, err = client.ProcessOCR(docType, fileURL, includeImageBase64)
-
-		if err != nil {
-			fmt.Printf("Error processing document: %v\n", err)
-			os.Exit(1)
-		}
 	}
 
+	opts := flagOCROptions()
+	opts.stdin = true
+	respData, err := ocrDocumentWith(ctx, provider, fileOrURL, opts, progressf)
 	if err != nil {
+		exitIfInterrupted(ctx)
 		fmt.Printf("Error processing document: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 
 	// Check if we received a valid response
@@ -155,7 +110,7 @@ func processAndConvertToMarkdown(fileOrURL string) {
 	}
 
 	// Save the JSON response
-	if err := os.WriteFile(jsonOutputPath, respData, 0644); err != nil {
+	if err := writeOutputFile(jsonOutputPath, respData); err != nil {
 		fmt.Printf("Error writing JSON file: %v\n", err)
 		os.Exit(1)
 	}
@@ -171,5 +126,5 @@ func processAndConvertToMarkdown(fileOrURL string) {
 	// (already handled by PreRun function, which sets singleFile to true if markdownFile is set)
 
 	// Convert JSON to markdown
-	convertJSONToMarkdown(jsonOutputPath)
+	convertJSONToMarkdown(ctx, jsonOutputPath)
 }
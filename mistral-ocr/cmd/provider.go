@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/tesseract"
+)
+
+var (
+	providerName  string
+	tesseractLang string
+	tesseractDPI  int
+)
+
+// OCRProvider runs OCR on documents and returns responses shaped like those
+// of the Mistral OCR API
+type OCRProvider interface {
+	// Model identifies the provider and its model, e.g. in cache keys
+	Model() string
+	// OCRURL runs req on a remote document
+	OCRURL(ctx context.Context, url string, req *mistral.OCRRequest) ([]byte, error)
+	// OCRFile runs req on a local file
+	OCRFile(ctx context.Context, filePath string, req *mistral.OCRRequest, logf func(string, ...interface{})) ([]byte, error)
+	// OCRReader runs req on the document read from r. name is the file name
+	// the document type is derived from and size its length, or -1.
+	OCRReader(ctx context.Context, r io.Reader, name string, size int64, req *mistral.OCRRequest, logf func(string, ...interface{})) ([]byte, error)
+}
+
+// mistralProvider runs OCR with the Mistral API
+type mistralProvider struct {
+	client *mistral.Client
+}
+
+func (p mistralProvider) Model() string {
+	return p.client.Model()
+}
+
+func (p mistralProvider) OCRURL(ctx context.Context, url string, req *mistral.OCRRequest) ([]byte, error) {
+	// Remote documents are fetched by the API directly
+	urlReq := *req
+	urlReq.Document = mistral.NewDocument(mistral.DocumentTypeFor(url), url)
+	return p.client.OCRRaw(ctx, &urlReq)
+}
+
+func (p mistralProvider) OCRFile(ctx context.Context, filePath string, req *mistral.OCRRequest, logf func(string, ...interface{})) ([]byte, error) {
+	return ocrLocalFile(ctx, p.client, filePath, req, logf)
+}
+
+func (p mistralProvider) OCRReader(ctx context.Context, r io.Reader, name string, size int64, req *mistral.OCRRequest, logf func(string, ...interface{})) ([]byte, error) {
+	return ocrReader(ctx, p.client, r, name, size, req, logf)
+}
+
+// tesseractProvider runs OCR locally, so documents never leave the machine.
+// It produces text only: no images, tables or annotations.
+type tesseractProvider struct {
+	engine *tesseract.Engine
+	// http downloads documents given by URL
+	http *http.Client
+}
+
+func (p tesseractProvider) Model() string {
+	return p.engine.Model()
+}
+
+// OCRURL downloads the document and runs OCR on the local copy
+func (p tesseractProvider) OCRURL(ctx context.Context, documentURL string, req *mistral.OCRRequest) ([]byte, error) {
+	name := "document.pdf"
+	if u, err := url.Parse(documentURL); err == nil && path.Ext(u.Path) != "" {
+		name = path.Base(u.Path)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, documentURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.http.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading document: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error downloading document: status %d", resp.StatusCode)
+	}
+
+	return p.OCRReader(ctx, resp.Body, name, resp.ContentLength, req, discardf)
+}
+
+// OCRReader copies the document to a temporary file for tesseract and
+// pdftoppm, which only read files
+func (p tesseractProvider) OCRReader(ctx context.Context, r io.Reader, name string, size int64, req *mistral.OCRRequest, logf func(string, ...interface{})) ([]byte, error) {
+	tmp, err := os.CreateTemp("", "mistral-ocr-input-*"+path.Ext(name))
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		return nil, fmt.Errorf("error reading document: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+
+	return p.OCRFile(ctx, tmp.Name(), req, logf)
+}
+
+func (p tesseractProvider) OCRFile(ctx context.Context, filePath string, req *mistral.OCRRequest, logf func(string, ...interface{})) ([]byte, error) {
+	if req.DocumentAnnotationFormat != nil || req.BBoxAnnotationFormat != nil {
+		return nil, fmt.Errorf("the tesseract provider does not support annotations")
+	}
+	if req.IncludeImageBase64 {
+		logf("Warning: the tesseract provider does not extract images\n")
+	}
+
+	logf("Running %s locally\n", p.engine.Model())
+	result, err := p.engine.OCR(ctx, filePath, req.Pages)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(result)
+}
+
+// newProvider returns the OCR provider selected with --provider
+func newProvider() OCRProvider {
+	switch name := flagOrEnv(providerName, "MISTRAL_OCR_PROVIDER"); name {
+	case "", "mistral":
+		return mistralProvider{client: newClient()}
+	case "tesseract":
+		// Nothing goes through the API client that records and replays
+		if recordDir != "" || replayDir != "" {
+			fmt.Println("Error: --record and --replay cannot be used with the tesseract provider")
+			os.Exit(1)
+		}
+		if _, err := exec.LookPath("tesseract"); err != nil {
+			fmt.Println("Error: the tesseract provider needs the tesseract command (and pdftoppm from poppler-utils for PDFs) in PATH")
+			os.Exit(1)
+		}
+		return tesseractProvider{engine: &tesseract.Engine{
+			Lang: flagOrEnv(tesseractLang, "MISTRAL_OCR_TESSERACT_LANG"),
+			DPI:  tesseractDPI,
+		}, http: downloadClient()}
+	default:
+		fmt.Printf("Error: unknown provider '%s' (expected mistral or tesseract)\n", name)
+		os.Exit(1)
+		return nil
+	}
+}
@@ -1,9 +1,19 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
 	"github.com/spf13/cobra"
 )
 
@@ -11,6 +21,31 @@ var (
 	// API key flag
 	apiKey string
 
+	// Client configuration flags
+	baseURL      string
+	model        string
+	timeout      time.Duration
+	proxyURL     string
+	extraHeaders []string
+	userAgent    string
+
+	// Retry policy flags
+	maxAttempts     int
+	retryDelay      time.Duration
+	retryMaxDelay   time.Duration
+	retryJitter     float64
+	retryStatusSpec string
+
+	// Rate limit flags, zero means unlimited
+	ocrRate           float64
+	ocrConcurrency    int
+	uploadRate        float64
+	uploadConcurrency int
+
+	// Record and replay directories of API interactions
+	recordDir string
+	replayDir string
+
 	// Root command
 	RootCmd = &cobra.Command{
 		Use:   "mistral-ocr",
@@ -23,17 +58,51 @@ It can process PDF documents and extract text maintaining document structure.`,
 func init() {
 	// Initialize API key from environment variable if not provided as a flag
 	RootCmd.PersistentFlags().StringVar(&apiKey, "api-key", "", "Mistral API key (defaults to MISTRAL_API_KEY env variable)")
+	RootCmd.PersistentFlags().StringVar(&baseURL, "base-url", "", "Mistral API base URL (defaults to MISTRAL_BASE_URL env variable or "+mistral.BaseURL+")")
+	RootCmd.PersistentFlags().StringVar(&model, "model", "", "OCR model (defaults to MISTRAL_OCR_MODEL env variable or "+mistral.DefaultModel+")")
+	RootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "Timeout for a single API request (defaults to MISTRAL_TIMEOUT env variable or 2m)")
+	RootCmd.PersistentFlags().StringVar(&proxyURL, "proxy", "", "Proxy URL for API requests (defaults to MISTRAL_PROXY env variable)")
+	RootCmd.PersistentFlags().StringArrayVar(&extraHeaders, "header", nil, "Extra 'Key: Value' header sent with every API request (repeatable, also MISTRAL_HEADERS as comma-separated list)")
+	RootCmd.PersistentFlags().StringVar(&userAgent, "user-agent", "", "User-Agent for API requests (defaults to MISTRAL_USER_AGENT env variable)")
+
+	retry := mistral.DefaultRetryPolicy()
+	RootCmd.PersistentFlags().IntVar(&maxAttempts, "max-attempts", retry.MaxAttempts, "Maximum attempts per API request, 1 disables retries")
+	RootCmd.PersistentFlags().DurationVar(&retryDelay, "retry-delay", retry.BaseDelay, "Delay before the first retry, doubled for every further retry")
+	RootCmd.PersistentFlags().DurationVar(&retryMaxDelay, "retry-max-delay", retry.MaxDelay, "Maximum delay between retries, also caps Retry-After")
+	RootCmd.PersistentFlags().Float64Var(&retryJitter, "retry-jitter", retry.Jitter, "Randomize retry delays by up to this fraction")
+	RootCmd.PersistentFlags().StringVar(&retryStatusSpec, "retry-statuses", formatStatuses(mistral.DefaultRetryStatuses), "HTTP statuses that are retried, e.g. 429,500-599")
+
+	RootCmd.PersistentFlags().Float64Var(&ocrRate, "ocr-rate", 0, "Maximum OCR requests started per second (0 for unlimited)")
+	RootCmd.PersistentFlags().IntVar(&ocrConcurrency, "ocr-concurrency", 0, "Maximum OCR requests in flight (0 for unlimited)")
+	RootCmd.PersistentFlags().Float64Var(&uploadRate, "upload-rate", 0, "Maximum file uploads started per second (0 for unlimited)")
+	RootCmd.PersistentFlags().IntVar(&uploadConcurrency, "upload-concurrency", 0, "Maximum file uploads in flight (0 for unlimited)")
 
 	// Add commands
 	RootCmd.AddCommand(processCmd)
 	RootCmd.AddCommand(convertCmd)
 	RootCmd.AddCommand(processMarkdownCmd)
+	RootCmd.AddCommand(batchCmd)
+	RootCmd.AddCommand(cacheCmd)
+	RootCmd.AddCommand(filesCmd)
+	RootCmd.AddCommand(extractCmd)
+	RootCmd.AddCommand(chunkCmd)
+	RootCmd.AddCommand(embedCmd)
+	RootCmd.AddCommand(askCmd)
+	RootCmd.AddCommand(serveCmd)
+	RootCmd.AddCommand(jobsCmd)
+	RootCmd.AddCommand(mcpCmd)
+	RootCmd.AddCommand(mockServerCmd)
 	RootCmd.AddCommand(versionCmd)
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
+// The command context is cancelled on Ctrl-C or SIGTERM.
 func Execute() {
-	if err := RootCmd.Execute(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := RootCmd.ExecuteContext(ctx)
+	stop()
+
+	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
@@ -53,3 +122,209 @@ func getAPIKey() string {
 
 	return apiKey
 }
+
+// flagOrEnv returns value if set and the environment variable env otherwise
+func flagOrEnv(value, env string) string {
+	if value != "" {
+		return value
+	}
+	return os.Getenv(env)
+}
+
+// apiTimeout returns the timeout set with --timeout or MISTRAL_TIMEOUT, zero if none
+func apiTimeout() time.Duration {
+	if timeout == 0 && os.Getenv("MISTRAL_TIMEOUT") != "" {
+		d, err := time.ParseDuration(os.Getenv("MISTRAL_TIMEOUT"))
+		if err != nil {
+			fmt.Printf("Error: invalid MISTRAL_TIMEOUT: %v\n", err)
+			os.Exit(1)
+		}
+		return d
+	}
+	return timeout
+}
+
+// downloadClient returns an HTTP client for fetching documents that honours
+// --timeout and --proxy like API requests do
+func downloadClient() *http.Client {
+	hc := &http.Client{Timeout: mistral.DefaultTimeout}
+	if d := apiTimeout(); d > 0 {
+		hc.Timeout = d
+	}
+
+	if proxy := flagOrEnv(proxyURL, "MISTRAL_PROXY"); proxy != "" {
+		u, err := url.Parse(proxy)
+		if err != nil {
+			fmt.Printf("Error: invalid proxy URL: %v\n", err)
+			os.Exit(1)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = http.ProxyURL(u)
+		hc.Transport = transport
+	}
+	return hc
+}
+
+// clientOptions builds the mistral client options from root flags and environment variables
+func clientOptions() []mistral.Option {
+	var opts []mistral.Option
+
+	if url := flagOrEnv(baseURL, "MISTRAL_BASE_URL"); url != "" {
+		opts = append(opts, mistral.WithBaseURL(url))
+	}
+	if m := flagOrEnv(model, "MISTRAL_OCR_MODEL"); m != "" {
+		opts = append(opts, mistral.WithModel(m))
+	}
+
+	if requestTimeout := apiTimeout(); requestTimeout > 0 {
+		opts = append(opts, mistral.WithTimeout(requestTimeout))
+	}
+
+	if proxy := flagOrEnv(proxyURL, "MISTRAL_PROXY"); proxy != "" {
+		opts = append(opts, mistral.WithProxy(proxy))
+	}
+
+	headers := extraHeaders
+	if len(headers) == 0 && os.Getenv("MISTRAL_HEADERS") != "" {
+		headers = strings.Split(os.Getenv("MISTRAL_HEADERS"), ",")
+	}
+	for _, header := range headers {
+		key, value, ok := strings.Cut(header, ":")
+		if !ok || strings.TrimSpace(key) == "" {
+			fmt.Printf("Error: invalid header %q, expected 'Key: Value'\n", header)
+			os.Exit(1)
+		}
+		opts = append(opts, mistral.WithHeader(strings.TrimSpace(key), strings.TrimSpace(value)))
+	}
+
+	retry, err := retryPolicy()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	opts = append(opts, mistral.WithRetryPolicy(retry))
+
+	if ocrRate < 0 || uploadRate < 0 || ocrConcurrency < 0 || uploadConcurrency < 0 {
+		fmt.Println("Error: rate limits must not be negative")
+		os.Exit(1)
+	}
+	opts = append(opts,
+		mistral.WithOCRLimit(mistral.Limit{Rate: ocrRate, Concurrency: ocrConcurrency}),
+		mistral.WithUploadLimit(mistral.Limit{Rate: uploadRate, Concurrency: uploadConcurrency}),
+	)
+
+	if recordDir != "" && replayDir != "" {
+		fmt.Println("Error: --record and --replay cannot be used together")
+		os.Exit(1)
+	}
+	// Recorded runs have to reach the API, replayed ones must not store results
+	if recordDir != "" {
+		recorder, err := mistral.NewRecorder(recordDir)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		opts = append(opts, mistral.WithRecorder(recorder))
+		noCache = true
+	}
+	if replayDir != "" {
+		replayer, err := mistral.NewReplayer(replayDir)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		opts = append(opts, mistral.WithTransport(replayer))
+		noCache = true
+	}
+
+	ua := flagOrEnv(userAgent, "MISTRAL_USER_AGENT")
+	if ua == "" {
+		ua = "mistral-ocr-cli/" + Version
+	}
+	opts = append(opts, mistral.WithUserAgent(ua))
+
+	return opts
+}
+
+// formatStatuses writes statuses in the --retry-statuses syntax, joining
+// consecutive statuses into ranges
+func formatStatuses(statuses map[int]bool) string {
+	var sorted []int
+	for status, ok := range statuses {
+		if ok {
+			sorted = append(sorted, status)
+		}
+	}
+	sort.Ints(sorted)
+
+	var parts []string
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] == sorted[j]+1 {
+			j++
+		}
+		if j > i {
+			parts = append(parts, fmt.Sprintf("%d-%d", sorted[i], sorted[j]))
+		} else {
+			parts = append(parts, strconv.Itoa(sorted[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
+
+// retryPolicy builds the retry policy from the retry flags
+func retryPolicy() (mistral.RetryPolicy, error) {
+	if maxAttempts < 1 {
+		return mistral.RetryPolicy{}, fmt.Errorf("--max-attempts must be at least 1")
+	}
+	if retryJitter < 0 || retryJitter > 1 {
+		return mistral.RetryPolicy{}, fmt.Errorf("--retry-jitter must be between 0 and 1")
+	}
+
+	statuses := make(map[int]bool)
+	for _, part := range strings.Split(retryStatusSpec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(from)
+		end := start
+		if err == nil && isRange {
+			end, err = strconv.Atoi(to)
+		}
+		if err != nil || start < 100 || end > 599 || end < start {
+			return mistral.RetryPolicy{}, fmt.Errorf("invalid status %q in --retry-statuses", part)
+		}
+		for status := start; status <= end; status++ {
+			statuses[status] = true
+		}
+	}
+
+	return mistral.RetryPolicy{
+		MaxAttempts: maxAttempts,
+		BaseDelay:   retryDelay,
+		MaxDelay:    retryMaxDelay,
+		Jitter:      retryJitter,
+		Statuses:    statuses,
+	}, nil
+}
+
+// newClient creates a Mistral client configured from the root flags
+func newClient() *mistral.Client {
+	// Replays do not reach the API, so they work without a key
+	key := flagOrEnv(apiKey, "MISTRAL_API_KEY")
+	if key == "" && replayDir != "" {
+		key = "replay"
+	} else {
+		key = getAPIKey()
+	}
+
+	client := mistral.NewClient(key, clientOptions()...)
+	if client == nil {
+		fmt.Println("Error: MISTRAL_API_KEY environment variable is not set and no --api-key flag was provided")
+		os.Exit(1)
+	}
+	return client
+}
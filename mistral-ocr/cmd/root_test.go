@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+)
+
+func TestRetryStatusesDefault(t *testing.T) {
+	if got, want := formatStatuses(mistral.DefaultRetryStatuses), "408,429,500,502-504"; got != want {
+		t.Errorf("formatStatuses(DefaultRetryStatuses) = %q, want %q", got, want)
+	}
+
+	// The default flag value parses back to the client's statuses
+	saved := retryStatusSpec
+	defer func() { retryStatusSpec = saved }()
+	retryStatusSpec = formatStatuses(mistral.DefaultRetryStatuses)
+	policy, err := retryPolicy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(policy.Statuses, mistral.DefaultRetryStatuses) {
+		t.Errorf("parsed statuses %v, want %v", policy.Statuses, mistral.DefaultRetryStatuses)
+	}
+}
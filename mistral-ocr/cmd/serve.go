@@ -0,0 +1,528 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveAddr            string
+	serveMaxUploadMB     int64
+	serveMaxConcurrent   int
+	serveShutdownTimeout time.Duration
+	serveJobWorkers      int
+	serveWebhookAllow    []string
+	serveJobRetention    string
+
+	serveCmd = &cobra.Command{
+		Use:   "serve",
+		Short: "Run an HTTP server exposing OCR as a service",
+		Long: `Run an HTTP server that exposes OCR and conversion to other applications.
+
+Endpoints:
+  GET  /healthz          health check
+  POST /v1/ocr           OCR an uploaded document (multipart/form-data, field "file")
+  POST /v1/ocr/url       OCR a remote document, body {"url": "https://..."}
+  POST /v1/convert       convert OCR JSON in the request body
+  POST /v1/jobs          queue a document for OCR, as an upload or {"url": "..."}
+  GET  /v1/jobs          list jobs
+  GET  /v1/jobs/{id}     job status
+  GET  /v1/jobs/{id}/result
+                         result of a finished job
+
+All POST endpoints accept the query parameters "format" (json, markdown, html, txt or
+jsonl; /v1/convert defaults to markdown, the others to json) and "pages" (e.g. 1-5,9);
+/v1/ocr/url and /v1/jobs also accept them in a JSON body. At most --max-concurrent
+documents are processed at a time; further requests wait for a free slot.
+
+Jobs are stored in --jobs-dir and processed by --job-workers workers. Jobs that were
+queued or running when the server stopped are resumed on the next start. If a job is
+submitted with a "webhook" URL, the final job status is POSTed to it as JSON. Any
+client can make the server POST to any http or https URL, including internal ones,
+unless --webhook-allow restricts the hosts. Finished jobs are kept until they are
+older than --job-retention; without it the job directory grows forever.`,
+		Args: cobra.NoArgs,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			if includeImages {
+				includeImageBase64 = true
+			}
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			runServer(cmd.Context())
+		},
+	}
+)
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "127.0.0.1:8080", "Address to listen on")
+	serveCmd.Flags().Int64Var(&serveMaxUploadMB, "max-upload-size", 100, "Maximum request body size in MB")
+	serveCmd.Flags().IntVar(&serveMaxConcurrent, "max-concurrent", 4, "Maximum number of documents processed at the same time")
+	serveCmd.Flags().DurationVar(&serveShutdownTimeout, "shutdown-timeout", 30*time.Second, "Time to let running requests finish on shutdown")
+	serveCmd.Flags().IntVar(&serveJobWorkers, "job-workers", 2, "Number of workers processing queued jobs")
+	serveCmd.Flags().StringSliceVar(&serveWebhookAllow, "webhook-allow", nil, "Hosts webhooks may be sent to, e.g. hooks.example.com,*.internal (default any host)")
+	serveCmd.Flags().StringVar(&serveJobRetention, "job-retention", "", "Delete finished jobs older than this age, e.g. 72h or 7d (default keep forever)")
+	serveCmd.Flags().StringVar(&jobsDir, "jobs-dir", "", "Job directory (defaults to MISTRAL_OCR_JOBS_DIR env variable or jobs/ in the cache dir)")
+	serveCmd.Flags().BoolVar(&includeImages, "images", false, "Include extracted images in rendered output")
+	serveCmd.Flags().BoolVar(&includePageBreaks, "page-breaks", true, "Include page break indicators between pages")
+	serveCmd.Flags().BoolVar(&titleFromFilename, "title-from-filename", true, "Use the document name as title")
+}
+
+// ocrServer handles the HTTP API
+type ocrServer struct {
+	client  *mistral.Client
+	jobs    *jobStore
+	slots   chan struct{}
+	maxBody int64
+}
+
+// apiHandler handles an API request, returning errors to be reported as JSON
+type apiHandler func(w http.ResponseWriter, r *http.Request) error
+
+// httpError is an error with the HTTP status it is reported with
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string {
+	return e.err.Error()
+}
+
+func (e *httpError) Unwrap() error {
+	return e.err
+}
+
+func badRequest(format string, a ...interface{}) error {
+	return &httpError{status: http.StatusBadRequest, err: fmt.Errorf(format, a...)}
+}
+
+// upstreamError returns the status and error an error of the OCR provider is
+// reported with, by the category of its API error. The server has no
+// authentication of its own, so a rejected key is the server's problem and
+// not reported as 401 to clients.
+func upstreamError(err error) (int, error) {
+	var apiErr *mistral.APIError
+	if !errors.As(err, &apiErr) {
+		return http.StatusBadGateway, err
+	}
+
+	switch apiErr.Category() {
+	case mistral.CategoryInvalidRequest:
+		return http.StatusBadRequest, err
+	case mistral.CategoryAuth:
+		return http.StatusBadGateway, fmt.Errorf("the server's Mistral API key was rejected: %w", err)
+	case mistral.CategoryQuota:
+		return http.StatusTooManyRequests, err
+	default:
+		return http.StatusBadGateway, err
+	}
+}
+
+func runServer(ctx context.Context) {
+	if serveMaxConcurrent < 1 || serveJobWorkers < 1 {
+		fmt.Println("Error: --max-concurrent and --job-workers must be at least 1")
+		os.Exit(1)
+	}
+	var retention time.Duration
+	if serveJobRetention != "" {
+		var err error
+		if retention, err = parseAge(serveJobRetention); err != nil || retention <= 0 {
+			fmt.Printf("Error: invalid --job-retention '%s'\n", serveJobRetention)
+			os.Exit(1)
+		}
+	}
+
+	s := &ocrServer{
+		client:  newClient(),
+		jobs:    openJobStoreOrExit(),
+		slots:   make(chan struct{}, serveMaxConcurrent),
+		maxBody: serveMaxUploadMB * 1024 * 1024,
+	}
+
+	resumed, err := s.jobs.recover()
+	if err != nil {
+		fmt.Printf("Error loading jobs: %v\n", err)
+		os.Exit(1)
+	}
+	if resumed > 0 {
+		fmt.Printf("Resuming %d queued jobs\n", resumed)
+	}
+
+	if retention > 0 {
+		go s.pruneJobs(ctx, retention)
+	}
+
+	// Workers stop with ctx; jobs they were running are queued again
+	var workers sync.WaitGroup
+	for i := 0; i < serveJobWorkers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			s.runJobWorker(ctx)
+		}()
+	}
+
+	srv := &http.Server{
+		Addr:              serveAddr,
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+		// Requests outlive the signal context so they can finish during shutdown
+		BaseContext: func(net.Listener) context.Context { return context.Background() },
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		fmt.Printf("Listening on http://%s\n", serveAddr)
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		fmt.Printf("Error running server: %v\n", err)
+		os.Exit(1)
+	case <-ctx.Done():
+	}
+
+	fmt.Println("Shutting down, waiting for running requests...")
+	s.jobs.close()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		srv.Close()
+		fmt.Printf("Error shutting down: %v\n", err)
+		os.Exit(1)
+	}
+	workers.Wait()
+}
+
+// pruneJobs removes jobs older than retention now and every hour until ctx is done
+func (s *ocrServer) pruneJobs(ctx context.Context, retention time.Duration) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		if n, err := s.jobs.prune(retention); err != nil {
+			fmt.Printf("Error pruning jobs: %v\n", err)
+		} else if n > 0 {
+			fmt.Printf("Removed %d jobs older than %s\n", n, serveJobRetention)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *ocrServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.handleHealth)
+	mux.HandleFunc("/v1/ocr", s.api(map[string]apiHandler{http.MethodPost: s.handleUpload}))
+	mux.HandleFunc("/v1/ocr/url", s.api(map[string]apiHandler{http.MethodPost: s.handleURL}))
+	mux.HandleFunc("/v1/convert", s.api(map[string]apiHandler{http.MethodPost: s.handleConvert}))
+	mux.HandleFunc("/v1/jobs", s.api(map[string]apiHandler{http.MethodGet: s.handleListJobs, http.MethodPost: s.handleSubmitJob}))
+	mux.HandleFunc("/v1/jobs/", s.api(map[string]apiHandler{http.MethodGet: s.handleJob}))
+	return logRequests(mux)
+}
+
+// api dispatches to the handler for the request method, limiting the
+// request size and reporting errors as JSON
+func (s *ocrServer) api(handlers map[string]apiHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler, ok := handlers[r.Method]
+		if !ok {
+			var allowed []string
+			for method := range handlers {
+				allowed = append(allowed, method)
+			}
+			sort.Strings(allowed)
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeJSONError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxBody)
+
+		if err := handler(w, r); err != nil {
+			var he *httpError
+			var tooLarge *http.MaxBytesError
+			switch {
+			case errors.As(err, &tooLarge):
+				writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("request body larger than %d MB", serveMaxUploadMB))
+			case errors.As(err, &he):
+				writeJSONError(w, he.status, he.err)
+			case r.Context().Err() != nil:
+				// The client went away; nobody is left to read a response
+			default:
+				status, err := upstreamError(err)
+				writeJSONError(w, status, err)
+			}
+		}
+	}
+}
+
+// acquire waits for a processing slot, returning false if the request is cancelled first
+func (s *ocrServer) acquire(ctx context.Context) bool {
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (s *ocrServer) release() {
+	<-s.slots
+}
+
+func (s *ocrServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":         "ok",
+		"version":        Version,
+		"active":         len(s.slots),
+		"max_concurrent": cap(s.slots),
+		"queued_jobs":    s.jobs.queued(),
+	})
+}
+
+// handleUpload runs OCR on the document uploaded in the "file" form field
+func (s *ocrServer) handleUpload(w http.ResponseWriter, r *http.Request) error {
+	format, err := requestFormat(r.URL.Query().Get("format"), "json")
+	if err != nil {
+		return err
+	}
+
+	path, name, err := receiveUpload(r, func(ext string) (*os.File, error) {
+		return os.CreateTemp("", "mistral-ocr-upload-*"+ext)
+	})
+	if path != "" {
+		defer os.Remove(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.ocrAndRespond(w, r, path, name, r.URL.Query().Get("pages"), format)
+}
+
+// receiveUpload saves the "file" part of a multipart request to a file made
+// by create and returns its path and the uploaded file name. The extension
+// is kept because it decides whether the document is sent as an image.
+func receiveUpload(r *http.Request, create func(ext string) (*os.File, error)) (string, string, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return "", "", badRequest("expected a multipart/form-data upload: %v", err)
+	}
+
+	var path, name string
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return path, name, badRequest("invalid multipart body: %w", err)
+		}
+		if part.FormName() != "file" || path != "" {
+			part.Close()
+			continue
+		}
+
+		name = filepath.Base(part.FileName())
+		if name == "." || name == string(filepath.Separator) {
+			return "", "", badRequest("the file part has no file name")
+		}
+		f, err := create(filepath.Ext(name))
+		if err != nil {
+			return "", "", err
+		}
+		path = f.Name()
+
+		_, err = io.Copy(f, part)
+		f.Close()
+		part.Close()
+		if err != nil {
+			return path, name, badRequest("error reading upload: %w", err)
+		}
+	}
+	if path == "" {
+		return "", "", badRequest("missing \"file\" field")
+	}
+	return path, name, nil
+}
+
+// handleURL runs OCR on a remote document
+func (s *ocrServer) handleURL(w http.ResponseWriter, r *http.Request) error {
+	var body struct {
+		URL    string `json:"url"`
+		Pages  string `json:"pages"`
+		Format string `json:"format"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return badRequest("invalid JSON body: %w", err)
+	}
+	if !isURL(body.URL) {
+		return badRequest("\"url\" must be an http or https URL")
+	}
+
+	query := r.URL.Query()
+	if body.Pages == "" {
+		body.Pages = query.Get("pages")
+	}
+	if body.Format == "" {
+		body.Format = query.Get("format")
+	}
+	format, err := requestFormat(body.Format, "json")
+	if err != nil {
+		return err
+	}
+
+	return s.ocrAndRespond(w, r, body.URL, body.URL, body.Pages, format)
+}
+
+// handleConvert renders OCR JSON sent in the request body
+func (s *ocrServer) handleConvert(w http.ResponseWriter, r *http.Request) error {
+	format, err := requestFormat(r.URL.Query().Get("format"), "markdown")
+	if err != nil {
+		return err
+	}
+	sel, err := parsePageSelection(r.URL.Query().Get("pages"))
+	if err != nil {
+		return badRequest("%v", err)
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return badRequest("error reading body: %w", err)
+	}
+	result, err := mistral.ParseOCRResult(data)
+	if err != nil {
+		return badRequest("%v", err)
+	}
+	filterPages(result, sel)
+
+	title := r.URL.Query().Get("title")
+	if title == "" {
+		title = "document"
+	}
+	return writeRendered(w, result, nil, title, format)
+}
+
+func (s *ocrServer) ocrAndRespond(w http.ResponseWriter, r *http.Request, fileOrURL, name, pages, format string) error {
+	if _, err := parsePageSelection(pages); err != nil {
+		return badRequest("%v", err)
+	}
+	if !s.acquire(r.Context()) {
+		return r.Context().Err()
+	}
+	defer s.release()
+
+	opts := ocrOptions{pages: pages, includeImageBase64: includeImageBase64}
+	data, err := ocrDocumentWith(r.Context(), mistralProvider{client: s.client}, fileOrURL, opts, discardf)
+	if err != nil {
+		return err
+	}
+
+	result, err := mistral.ParseOCRResult(data)
+	if err != nil {
+		return err
+	}
+	return writeRendered(w, result, data, name, format)
+}
+
+// serverFormats maps the format parameter to the response content type
+var serverFormats = map[string]string{
+	"json":     "application/json",
+	"markdown": "text/markdown; charset=utf-8",
+	"html":     "text/html; charset=utf-8",
+	"txt":      "text/plain; charset=utf-8",
+	"jsonl":    "application/x-ndjson",
+}
+
+func requestFormat(format, fallback string) (string, error) {
+	if format == "" {
+		return fallback, nil
+	}
+	if _, ok := serverFormats[format]; !ok {
+		return "", badRequest("unsupported format '%s' (expected json, markdown, html, txt or jsonl)", format)
+	}
+	return format, nil
+}
+
+// writeRendered writes result in format. raw is the original OCR JSON, if any.
+func writeRendered(w http.ResponseWriter, result *mistral.OCRResult, raw []byte, name, format string) error {
+	var body []byte
+	if format == "json" {
+		if raw == nil {
+			var err error
+			if raw, err = json.Marshal(result); err != nil {
+				return err
+			}
+		}
+		body = raw
+	} else {
+		// Image files are never written by the server, so only inlining applies
+		content, err := outputFormats[format].render(result, name, "", "")
+		if err != nil {
+			return err
+		}
+		body = []byte(content)
+	}
+
+	w.Header().Set("Content-Type", serverFormats[format])
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write(body)
+	return err
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// statusRecorder remembers the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// logRequests prints one line per request
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		fmt.Printf("%s %s %s %d %s\n", start.Format(time.RFC3339), r.Method, r.URL.Path, rec.status, time.Since(start).Round(time.Millisecond))
+	})
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+)
+
+func TestUpstreamError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+		// message is expected in the reported error
+		message string
+	}{
+		{err: &mistral.APIError{StatusCode: 400}, want: http.StatusBadRequest},
+		{err: &mistral.APIError{StatusCode: 422}, want: http.StatusBadRequest},
+		// Clients cannot fix the server's key, so it is an upstream failure
+		{err: &mistral.APIError{StatusCode: 401}, want: http.StatusBadGateway, message: "server's Mistral API key was rejected"},
+		{err: &mistral.APIError{StatusCode: 403}, want: http.StatusBadGateway, message: "server's Mistral API key was rejected"},
+		{err: &mistral.APIError{StatusCode: 429}, want: http.StatusTooManyRequests},
+		{err: &mistral.APIError{StatusCode: 503}, want: http.StatusBadGateway},
+		{err: fmt.Errorf("OCR failed: %w", &mistral.APIError{StatusCode: 429}), want: http.StatusTooManyRequests},
+		{err: errors.New("connection refused"), want: http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			status, err := upstreamError(tt.err)
+			if status != tt.want {
+				t.Errorf("status = %d, want %d", status, tt.want)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("reported error %v does not wrap %v", err, tt.err)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("reported error %q does not mention %q", err, tt.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/pdf"
+)
+
+// ocrSplitPDF splits a PDF that exceeds mistral.MaxFileSize into page range
+// chunks, runs OCR on each chunk and stitches the results into a single
+// response with page indices relative to the whole document
+func ocrSplitPDF(ctx context.Context, client *mistral.Client, data []byte, name string, req *mistral.OCRRequest, logf func(string, ...interface{})) ([]byte, error) {
+	doc, err := pdf.NewReader(data)
+	if err != nil {
+		return nil, fmt.Errorf("error reading PDF for splitting: %v", err)
+	}
+
+	chunks, err := doc.Split(mistral.MaxFileSize)
+	if err != nil {
+		return nil, fmt.Errorf("error splitting PDF: %v", err)
+	}
+
+	logf("File exceeds %d MB, split %d pages into %d parts\n", mistral.MaxFileSize/1024/1024, doc.NumPages(), len(chunks))
+
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	parts := make([]*mistral.OCRResult, len(chunks))
+	offsets := make([]int, len(chunks))
+
+	for i, chunk := range chunks {
+		// Translate the requested pages into indices within the chunk
+		chunkReq := *req
+		if len(req.Pages) > 0 {
+			chunkReq.Pages = nil
+			for _, p := range req.Pages {
+				if p >= chunk.FirstPage && p < chunk.FirstPage+chunk.PageCount {
+					chunkReq.Pages = append(chunkReq.Pages, p-chunk.FirstPage)
+				}
+			}
+			if len(chunkReq.Pages) == 0 {
+				continue
+			}
+		}
+
+		logf("Processing part %d/%d (pages %d-%d)\n", i+1, len(chunks), chunk.FirstPage+1, chunk.FirstPage+chunk.PageCount)
+
+		chunkName := fmt.Sprintf("%s.part%d.pdf", base, i+1)
+		respData, err := ocrUploadedReader(ctx, client, bytes.NewReader(chunk.Data), chunkName, int64(len(chunk.Data)), &chunkReq, logf)
+		if err != nil {
+			return nil, fmt.Errorf("part %d (pages %d-%d): %w", i+1, chunk.FirstPage+1, chunk.FirstPage+chunk.PageCount, err)
+		}
+
+		if parts[i], err = mistral.ParseOCRResult(respData); err != nil {
+			return nil, err
+		}
+		offsets[i] = chunk.FirstPage
+	}
+
+	// Drop the slots of chunks without selected pages
+	var results []*mistral.OCRResult
+	var resultOffsets []int
+	for i, part := range parts {
+		if part != nil {
+			results = append(results, part)
+			resultOffsets = append(resultOffsets, offsets[i])
+		}
+	}
+
+	return json.Marshal(mistral.MergeOCRResults(results, resultOffsets))
+}
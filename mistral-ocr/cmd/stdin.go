@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+)
+
+// stdinArg is the input argument that reads the document from stdin
+const stdinArg = "-"
+
+var stdinDocument struct {
+	once sync.Once
+	data []byte
+	name string
+	err  error
+}
+
+// readStdin returns the document piped to stdin and a file name matching its
+// detected type, e.g. stdin.pdf. stdin can only be read once, so the document
+// is kept in memory for later calls.
+func readStdin() ([]byte, string, error) {
+	doc := &stdinDocument
+	doc.once.Do(func() {
+		if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			doc.err = fmt.Errorf("no document piped to stdin")
+			return
+		}
+
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			doc.err = fmt.Errorf("error reading stdin: %v", err)
+			return
+		}
+		if len(data) == 0 {
+			doc.err = fmt.Errorf("no document piped to stdin")
+			return
+		}
+
+		contentType := mistral.DetectContentType(data[:min(len(data), mistral.SniffLen)])
+		ext := mistral.ContentTypeExtension(contentType)
+		if ext == "" {
+			doc.err = fmt.Errorf("unsupported document type %s on stdin", contentType)
+			return
+		}
+		doc.data, doc.name = data, "stdin"+ext
+	})
+	return doc.data, doc.name, doc.err
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/markdown"
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+)
+
+// pageText returns the plain text of page
+func pageText(page mistral.Page, outputDir, linkDir string) (string, error) {
+	return markdown.Text(markdown.Parse(page.Markdown)) + "\n", nil
+}
+
+// renderText combines the plain text of all pages of result. Pages are
+// separated by a form feed, as pdftotext does, when page breaks are enabled.
+func renderText(result *mistral.OCRResult, sourceFile, outputDir, linkDir string) (string, error) {
+	separator := "\n"
+	if includePageBreaks {
+		separator = "\f"
+	}
+
+	var pages []string
+	for _, page := range result.Pages {
+		text, err := pageText(page, outputDir, linkDir)
+		if err != nil {
+			return "", err
+		}
+		pages = append(pages, text)
+	}
+	return strings.Join(pages, separator), nil
+}
+
+// jsonlPage is a line of the jsonl output format
+type jsonlPage struct {
+	Index      int                `json:"index"`
+	Text       string             `json:"text"`
+	Markdown   string             `json:"markdown"`
+	Dimensions mistral.Dimensions `json:"dimensions"`
+	ImageCount int                `json:"image_count"`
+}
+
+// renderJSONL writes one JSON object per page of result
+func renderJSONL(result *mistral.OCRResult, sourceFile, outputDir, linkDir string) (string, error) {
+	var b strings.Builder
+	for _, page := range result.Pages {
+		content, err := pageMarkdown(page, outputDir, linkDir)
+		if err != nil {
+			return "", fmt.Errorf("page %d: %v", page.Index+1, err)
+		}
+
+		line, err := json.Marshal(jsonlPage{
+			Index:      page.Index,
+			Text:       markdown.Text(markdown.Parse(page.Markdown)),
+			Markdown:   content,
+			Dimensions: page.Dimensions,
+			ImageCount: len(page.Images),
+		})
+		if err != nil {
+			return "", err
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,266 @@
+// Package chunk splits OCR Markdown into retrieval sized chunks. Sections
+// are cut at headings, long sections are packed into chunks of at most the
+// configured size with an overlap of trailing words, and tables, code
+// fences and math blocks are never cut apart.
+package chunk
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/markdown"
+)
+
+// Unit is what chunk sizes are measured in
+type Unit int
+
+const (
+	// Tokens estimates tokens as one per four characters, which is close
+	// enough for budgeting with common embedding and chat tokenizers
+	Tokens Unit = iota
+	Chars
+)
+
+// Options controls the size of chunks
+type Options struct {
+	// Size is the maximum size of a chunk. Tables, code and math blocks
+	// larger than Size become a chunk of their own.
+	Size int
+	// Overlap is the size of the text repeated from the end of a chunk at
+	// the start of the next one within the same section
+	Overlap int
+	Unit    Unit
+}
+
+// Page is the Markdown of a single page
+type Page struct {
+	// Number is the one-based page number
+	Number   int
+	Markdown string
+}
+
+// Chunk is a piece of a document with the metadata needed to cite it
+type Chunk struct {
+	Index       int      `json:"index"`
+	Source      string   `json:"source,omitempty"`
+	Pages       []int    `json:"pages"`
+	HeadingPath []string `json:"heading_path"`
+	Text        string   `json:"text"`
+	Tokens      int      `json:"tokens"`
+	Chars       int      `json:"chars"`
+}
+
+// EstimateTokens returns the approximate token count of text
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// piece is a block, or part of a long block, that is placed into chunks as a whole
+type piece struct {
+	text    string
+	page    int
+	atomic  bool
+	heading bool
+}
+
+type splitter struct {
+	opts Options
+}
+
+func (s *splitter) measure(text string) int {
+	if s.opts.Unit == Chars {
+		return utf8.RuneCountInString(text)
+	}
+	return EstimateTokens(text)
+}
+
+func joinPieces(pieces []piece) string {
+	texts := make([]string, len(pieces))
+	for i, p := range pieces {
+		texts[i] = p.text
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// Split chunks the pages of a document in order
+func Split(pages []Page, opts Options) []Chunk {
+	s := &splitter{opts: opts}
+
+	var chunks []Chunk
+	var section []piece
+	var path []string
+	var levels []int
+	sectionPath := []string{}
+
+	flush := func() {
+		for _, p := range section {
+			if !p.heading {
+				// Sections holding nothing but their heading are covered by the
+				// heading path of the chunks that follow
+				chunks = append(chunks, s.pack(section, sectionPath)...)
+				break
+			}
+		}
+		section = nil
+	}
+
+	for _, page := range pages {
+		for _, b := range markdown.Parse(page.Markdown) {
+			if b.Kind == markdown.Heading {
+				flush()
+				for len(levels) > 0 && levels[len(levels)-1] >= b.Level {
+					levels = levels[:len(levels)-1]
+					path = path[:len(path)-1]
+				}
+				levels = append(levels, b.Level)
+				path = append(path, strings.TrimSpace(markdown.InlineText(b.Text)))
+				sectionPath = append([]string{}, path...)
+			}
+
+			atomic := b.Kind == markdown.Table || b.Kind == markdown.CodeBlock || b.Kind == markdown.MathBlock
+			section = append(section, piece{text: b.Source, page: page.Number, atomic: atomic, heading: b.Kind == markdown.Heading})
+		}
+	}
+	flush()
+
+	for i := range chunks {
+		chunks[i].Index = i
+	}
+	return chunks
+}
+
+// pack fills chunks with the pieces of one section
+func (s *splitter) pack(section []piece, path []string) []Chunk {
+	var chunks []Chunk
+	var current []piece
+
+	// Long prose is cut short enough for the overlap of the previous part to
+	// fit in front of it
+	limit := s.opts.Size
+	if s.opts.Overlap > 0 {
+		if l := s.opts.Size - s.opts.Overlap - s.measure("\n\n"); l > 0 {
+			limit = l
+		}
+	}
+
+	emit := func() {
+		text := joinPieces(current)
+		chunks = append(chunks, Chunk{
+			Pages:       piecePages(current),
+			HeadingPath: path,
+			Text:        text,
+			Tokens:      EstimateTokens(text),
+			Chars:       utf8.RuneCountInString(text),
+		})
+	}
+
+	for _, p := range section {
+		parts := []piece{p}
+		if !p.atomic && s.measure(p.text) > s.opts.Size {
+			parts = nil
+			for _, text := range s.splitProse(p.text, limit) {
+				parts = append(parts, piece{text: text, page: p.page})
+			}
+		}
+
+		for _, part := range parts {
+			if len(current) > 0 && s.measure(joinPieces(append(current, part))) > s.opts.Size {
+				emit()
+				current = s.overlap(current)
+				if len(current) > 0 && s.measure(joinPieces(append(current, part))) > s.opts.Size {
+					current = nil
+				}
+			}
+			current = append(current, part)
+		}
+	}
+	if len(current) > 0 {
+		emit()
+	}
+
+	return chunks
+}
+
+// overlap returns the trailing words of the last prose piece of a chunk
+func (s *splitter) overlap(pieces []piece) []piece {
+	if s.opts.Overlap <= 0 || len(pieces) == 0 {
+		return nil
+	}
+	last := pieces[len(pieces)-1]
+	if last.atomic || last.heading {
+		return nil
+	}
+
+	words := strings.Fields(last.text)
+	start := len(words)
+	for start > 0 && s.measure(strings.Join(words[start-1:], " ")) <= s.opts.Overlap {
+		start--
+	}
+	if start == len(words) {
+		return nil
+	}
+	return []piece{{text: strings.Join(words[start:], " "), page: last.page}}
+}
+
+// splitProse cuts text that exceeds limit at line breaks, then sentence
+// ends, then spaces, and as a last resort in the middle of a word
+func (s *splitter) splitProse(text string, limit int) []string {
+	text = strings.TrimSpace(text)
+	if s.measure(text) <= limit {
+		return []string{text}
+	}
+
+	for _, sep := range []string{"\n", ". ", " "} {
+		parts := strings.SplitAfter(text, sep)
+		if len(parts) < 2 {
+			continue
+		}
+
+		var segments []string
+		current := ""
+		for _, part := range parts {
+			if current != "" && s.measure(current+part) > limit {
+				segments = append(segments, current)
+				current = ""
+			}
+			current += part
+		}
+		if current != "" {
+			segments = append(segments, current)
+		}
+
+		var result []string
+		for _, segment := range segments {
+			if segment = strings.TrimSpace(segment); segment != "" {
+				result = append(result, s.splitProse(segment, limit)...)
+			}
+		}
+		return result
+	}
+
+	n := limit
+	if s.opts.Unit == Tokens {
+		n *= 4
+	}
+	var result []string
+	runes := []rune(text)
+	for len(runes) > n {
+		result = append(result, string(runes[:n]))
+		runes = runes[n:]
+	}
+	return append(result, string(runes))
+}
+
+func piecePages(pieces []piece) []int {
+	seen := make(map[int]bool)
+	var pages []int
+	for _, p := range pieces {
+		if !seen[p.page] {
+			seen[p.page] = true
+			pages = append(pages, p.page)
+		}
+	}
+	sort.Ints(pages)
+	return pages
+}
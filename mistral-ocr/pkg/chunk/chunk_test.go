@@ -0,0 +1,194 @@
+package chunk
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func words(n int) string {
+	w := make([]string, n)
+	for i := range w {
+		w[i] = "word" + string(rune('a'+i%26))
+	}
+	return strings.Join(w, " ")
+}
+
+func TestSplitSections(t *testing.T) {
+	pages := []Page{
+		{Number: 1, Markdown: "# Report\n\nIntro text.\n\n## Scope\n\nScope text."},
+		{Number: 2, Markdown: "## Results\n\n### Empty\n\n### Tables\n\nResults text."},
+	}
+	chunks := Split(pages, Options{Size: 1000, Unit: Chars})
+
+	want := []struct {
+		path  []string
+		pages []int
+		text  string
+	}{
+		{path: []string{"Report"}, pages: []int{1}, text: "# Report\n\nIntro text."},
+		{path: []string{"Report", "Scope"}, pages: []int{1}, text: "## Scope\n\nScope text."},
+		// The section holding only its heading is dropped
+		{path: []string{"Report", "Results", "Tables"}, pages: []int{2}, text: "### Tables\n\nResults text."},
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("got %d chunks %+v, want %d", len(chunks), chunks, len(want))
+	}
+	for i, w := range want {
+		c := chunks[i]
+		if c.Index != i {
+			t.Errorf("chunk %d has index %d", i, c.Index)
+		}
+		if !reflect.DeepEqual(c.HeadingPath, w.path) {
+			t.Errorf("chunk %d heading path = %q, want %q", i, c.HeadingPath, w.path)
+		}
+		if !reflect.DeepEqual(c.Pages, w.pages) {
+			t.Errorf("chunk %d pages = %v, want %v", i, c.Pages, w.pages)
+		}
+		if c.Text != w.text {
+			t.Errorf("chunk %d text = %q, want %q", i, c.Text, w.text)
+		}
+		if c.Chars != utf8.RuneCountInString(c.Text) || c.Tokens != EstimateTokens(c.Text) {
+			t.Errorf("chunk %d has wrong sizes %d chars, %d tokens", i, c.Chars, c.Tokens)
+		}
+	}
+}
+
+func TestSplitSectionAcrossPages(t *testing.T) {
+	pages := []Page{
+		{Number: 3, Markdown: "# Notes\n\nFirst page."},
+		{Number: 4, Markdown: "Second page."},
+	}
+	chunks := Split(pages, Options{Size: 1000, Unit: Chars})
+	if len(chunks) != 1 {
+		t.Fatalf("got %d chunks, want 1", len(chunks))
+	}
+	if want := []int{3, 4}; !reflect.DeepEqual(chunks[0].Pages, want) {
+		t.Errorf("pages = %v, want %v", chunks[0].Pages, want)
+	}
+}
+
+func TestSplitSize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		opts Options
+	}{
+		{name: "paragraphs", text: strings.Repeat(words(12)+"\n\n", 10), opts: Options{Size: 200, Unit: Chars}},
+		{name: "long paragraph", text: words(300), opts: Options{Size: 150, Unit: Chars}},
+		{name: "sentences", text: strings.Repeat("A short sentence here. ", 40), opts: Options{Size: 100, Unit: Chars}},
+		{name: "tokens", text: words(400), opts: Options{Size: 50, Unit: Tokens}},
+		{name: "single long word", text: strings.Repeat("x", 1000), opts: Options{Size: 64, Unit: Chars}},
+		{name: "with overlap", text: words(300), opts: Options{Size: 150, Overlap: 30, Unit: Chars}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := Split([]Page{{Number: 1, Markdown: tt.text}}, tt.opts)
+			if len(chunks) < 2 {
+				t.Fatalf("got %d chunks, want the text split", len(chunks))
+			}
+			s := &splitter{opts: tt.opts}
+			for i, c := range chunks {
+				if size := s.measure(c.Text); size > tt.opts.Size {
+					t.Errorf("chunk %d has size %d, more than %d: %q", i, size, tt.opts.Size, c.Text)
+				}
+			}
+			if tt.opts.Overlap == 0 {
+				// Without overlap the chunks hold the text exactly once
+				var got []string
+				for _, c := range chunks {
+					got = append(got, strings.Fields(c.Text)...)
+				}
+				if got, want := strings.Join(got, ""), strings.Join(strings.Fields(tt.text), ""); got != want {
+					t.Errorf("chunks hold %d characters of text, want %d", len(got), len(want))
+				}
+			}
+		})
+	}
+}
+
+func TestSplitOverlap(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		opts Options
+	}{
+		{name: "long paragraph", text: words(100), opts: Options{Size: 100, Overlap: 20, Unit: Chars}},
+		{name: "paragraphs", text: strings.Repeat(words(6)+"\n\n", 15), opts: Options{Size: 100, Overlap: 20, Unit: Chars}},
+		{name: "tokens", text: words(200), opts: Options{Size: 30, Overlap: 5, Unit: Tokens}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := Split([]Page{{Number: 1, Markdown: tt.text}}, tt.opts)
+			if len(chunks) < 3 {
+				t.Fatalf("got %d chunks, want at least 3", len(chunks))
+			}
+
+			s := &splitter{opts: tt.opts}
+			for i := 1; i < len(chunks); i++ {
+				if size := s.measure(chunks[i].Text); size > tt.opts.Size {
+					t.Errorf("chunk %d has size %d, more than %d", i, size, tt.opts.Size)
+				}
+
+				// The chunk starts with the longest run of trailing words of
+				// the previous chunk that fits in the overlap
+				prev := strings.Fields(chunks[i-1].Text)
+				next := strings.Fields(chunks[i].Text)
+				n := 0
+				for n < len(prev) && s.measure(strings.Join(prev[len(prev)-n-1:], " ")) <= tt.opts.Overlap {
+					n++
+				}
+				if n == 0 {
+					t.Fatalf("no overlap between chunks %d and %d", i-1, i)
+				}
+				if !reflect.DeepEqual(next[:n], prev[len(prev)-n:]) {
+					t.Errorf("chunk %d starts with %q, want the overlap %q", i, next[:n], prev[len(prev)-n:])
+				}
+			}
+		})
+	}
+}
+
+func TestSplitNoOverlapAcrossSections(t *testing.T) {
+	chunks := Split([]Page{{Number: 1, Markdown: "# A\n\n" + words(30) + "\n\n# B\n\nSecond section."}}, Options{Size: 1000, Overlap: 50, Unit: Chars})
+	if len(chunks) != 2 {
+		t.Fatalf("got %d chunks, want 2", len(chunks))
+	}
+	if want := "# B\n\nSecond section."; chunks[1].Text != want {
+		t.Errorf("second section = %q, want %q", chunks[1].Text, want)
+	}
+}
+
+func TestSplitKeepsAtomicBlocks(t *testing.T) {
+	table := "| a | b |\n| --- | --- |\n" + strings.Repeat("| 1 | 2 |\n", 20)
+	table = strings.TrimSuffix(table, "\n")
+	code := "```\n" + strings.Repeat("line of code\n", 15) + "```"
+	src := "Before the table.\n\n" + table + "\n\n" + code + "\n\nAfter the code."
+
+	chunks := Split([]Page{{Number: 1, Markdown: src}}, Options{Size: 80, Overlap: 10, Unit: Chars})
+
+	var foundTable, foundCode bool
+	for _, c := range chunks {
+		if strings.Contains(c.Text, "| 1 | 2 |") {
+			if c.Text != table {
+				t.Errorf("table chunk = %q, want the whole table alone", c.Text)
+			}
+			foundTable = true
+		}
+		if strings.Contains(c.Text, "line of code") {
+			if c.Text != code {
+				t.Errorf("code chunk = %q, want the whole code block alone", c.Text)
+			}
+			foundCode = true
+		}
+	}
+	if !foundTable || !foundCode {
+		t.Errorf("table or code block missing from chunks %+v", chunks)
+	}
+	if last := chunks[len(chunks)-1]; last.Text != "After the code." {
+		t.Errorf("last chunk = %q, want no overlap after a code block", last.Text)
+	}
+}
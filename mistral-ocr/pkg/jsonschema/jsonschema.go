@@ -0,0 +1,380 @@
+// Package jsonschema validates JSON documents against the commonly used
+// subset of JSON Schema (draft 7 / 2020-12) needed for annotation formats:
+// type, enum, const, properties, required, additionalProperties, items,
+// string, number and array bounds, pattern, allOf/anyOf/oneOf/not and local
+// $ref pointers into definitions or $defs.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is a compiled JSON Schema
+type Schema struct {
+	root     interface{}
+	patterns map[string]*regexp.Regexp
+}
+
+// ValidationError lists every violation found in a document
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("document does not match schema: %s", strings.Join(e.Problems, "; "))
+}
+
+// Compile parses a JSON Schema document
+func Compile(data []byte) (*Schema, error) {
+	var root interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %v", err)
+	}
+	switch root.(type) {
+	case map[string]interface{}, bool:
+	default:
+		return nil, fmt.Errorf("invalid JSON schema: must be an object or boolean")
+	}
+
+	s := &Schema{root: root, patterns: make(map[string]*regexp.Regexp)}
+	if err := s.compilePatterns(root); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// compilePatterns precompiles every "pattern" keyword so invalid expressions
+// are reported when the schema is loaded
+func (s *Schema) compilePatterns(node interface{}) error {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if p, ok := n["pattern"].(string); ok {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return fmt.Errorf("invalid pattern %q in schema: %v", p, err)
+			}
+			s.patterns[p] = re
+		}
+		for _, v := range n {
+			if err := s.compilePatterns(v); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, v := range n {
+			if err := s.compilePatterns(v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateJSON decodes data and validates it
+func (s *Schema) ValidateJSON(data []byte) error {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("invalid JSON: %v", err)
+	}
+	return s.Validate(doc)
+}
+
+// Validate checks a decoded JSON value (as produced by encoding/json into
+// interface{}) and returns a *ValidationError if it does not match
+func (s *Schema) Validate(doc interface{}) error {
+	var problems []string
+	s.validate(s.root, doc, "$", &problems, 0)
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// maxDepth stops runaway recursion through self-referencing schemas
+const maxDepth = 64
+
+func (s *Schema) validate(schema, value interface{}, path string, problems *[]string, depth int) {
+	if depth > maxDepth {
+		*problems = append(*problems, fmt.Sprintf("%s: schema nesting too deep", path))
+		return
+	}
+
+	switch sc := schema.(type) {
+	case bool:
+		if !sc {
+			*problems = append(*problems, fmt.Sprintf("%s: no value is allowed here", path))
+		}
+		return
+	case map[string]interface{}:
+		s.validateObjectSchema(sc, value, path, problems, depth)
+	}
+}
+
+func (s *Schema) validateObjectSchema(sc map[string]interface{}, value interface{}, path string, problems *[]string, depth int) {
+	fail := func(format string, a ...interface{}) {
+		*problems = append(*problems, path+": "+fmt.Sprintf(format, a...))
+	}
+
+	if ref, ok := sc["$ref"].(string); ok {
+		target, err := s.resolveRef(ref)
+		if err != nil {
+			fail("%v", err)
+		} else {
+			s.validate(target, value, path, problems, depth+1)
+		}
+	}
+
+	if t, ok := sc["type"]; ok && !matchesType(t, value) {
+		fail("expected %s, got %s", describeType(t), jsonType(value))
+		return
+	}
+
+	if enum, ok := sc["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if equal(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("value %s is not one of the allowed values", compact(value))
+		}
+	}
+	if c, ok := sc["const"]; ok && !equal(c, value) {
+		fail("value must be %s", compact(c))
+	}
+
+	for _, sub := range asList(sc["allOf"]) {
+		s.validate(sub, value, path, problems, depth+1)
+	}
+	if anyOf := asList(sc["anyOf"]); len(anyOf) > 0 && s.countMatches(anyOf, value, path, depth) == 0 {
+		fail("value does not match any of the allowed schemas")
+	}
+	if oneOf := asList(sc["oneOf"]); len(oneOf) > 0 {
+		if n := s.countMatches(oneOf, value, path, depth); n != 1 {
+			fail("value must match exactly one schema, matched %d", n)
+		}
+	}
+	if not, ok := sc["not"]; ok && s.countMatches([]interface{}{not}, value, path, depth) == 1 {
+		fail("value must not match the schema")
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if n, ok := number(sc["minLength"]); ok && float64(length) < n {
+			fail("string shorter than %v characters", n)
+		}
+		if n, ok := number(sc["maxLength"]); ok && float64(length) > n {
+			fail("string longer than %v characters", n)
+		}
+		if p, ok := sc["pattern"].(string); ok && !s.patterns[p].MatchString(v) {
+			fail("string does not match pattern %q", p)
+		}
+	case float64:
+		if n, ok := number(sc["minimum"]); ok && v < n {
+			fail("%v is less than minimum %v", v, n)
+		}
+		if n, ok := number(sc["maximum"]); ok && v > n {
+			fail("%v is greater than maximum %v", v, n)
+		}
+		if n, ok := number(sc["exclusiveMinimum"]); ok && v <= n {
+			fail("%v must be greater than %v", v, n)
+		}
+		if n, ok := number(sc["exclusiveMaximum"]); ok && v >= n {
+			fail("%v must be less than %v", v, n)
+		}
+		if n, ok := number(sc["multipleOf"]); ok && n > 0 {
+			if q := v / n; math.Abs(q-math.Round(q)) > 1e-9 {
+				fail("%v is not a multiple of %v", v, n)
+			}
+		}
+	case []interface{}:
+		if n, ok := number(sc["minItems"]); ok && float64(len(v)) < n {
+			fail("array has fewer than %v items", n)
+		}
+		if n, ok := number(sc["maxItems"]); ok && float64(len(v)) > n {
+			fail("array has more than %v items", n)
+		}
+		if unique, _ := sc["uniqueItems"].(bool); unique {
+			for i := range v {
+				for j := i + 1; j < len(v); j++ {
+					if equal(v[i], v[j]) {
+						fail("array items %d and %d are equal", i, j)
+					}
+				}
+			}
+		}
+
+		// prefixItems (2020-12) or an items array (draft 7) validate by position
+		prefix := asList(sc["prefixItems"])
+		if tuple, ok := sc["items"].([]interface{}); ok {
+			prefix = tuple
+		}
+		for i, item := range v {
+			itemPath := path + "[" + strconv.Itoa(i) + "]"
+			if i < len(prefix) {
+				s.validate(prefix[i], item, itemPath, problems, depth+1)
+			} else if items, ok := sc["items"]; ok {
+				if _, isTuple := items.([]interface{}); !isTuple {
+					s.validate(items, item, itemPath, problems, depth+1)
+				}
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range asList(sc["required"]) {
+			key, _ := name.(string)
+			if _, ok := v[key]; !ok {
+				fail("missing required property %q", key)
+			}
+		}
+		if n, ok := number(sc["minProperties"]); ok && float64(len(v)) < n {
+			fail("object has fewer than %v properties", n)
+		}
+		if n, ok := number(sc["maxProperties"]); ok && float64(len(v)) > n {
+			fail("object has more than %v properties", n)
+		}
+
+		props, _ := sc["properties"].(map[string]interface{})
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			propPath := path + "." + k
+			if propSchema, ok := props[k]; ok {
+				s.validate(propSchema, v[k], propPath, problems, depth+1)
+				continue
+			}
+			if additional, ok := sc["additionalProperties"]; ok {
+				if allowed, isBool := additional.(bool); isBool && !allowed {
+					fail("unexpected property %q", k)
+					continue
+				}
+				s.validate(additional, v[k], propPath, problems, depth+1)
+			}
+		}
+	}
+}
+
+// countMatches returns how many of schemas accept value
+func (s *Schema) countMatches(schemas []interface{}, value interface{}, path string, depth int) int {
+	n := 0
+	for _, sub := range schemas {
+		var problems []string
+		s.validate(sub, value, path, &problems, depth+1)
+		if len(problems) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// resolveRef follows a local JSON pointer such as #/$defs/address
+func (s *Schema) resolveRef(ref string) (interface{}, error) {
+	if ref == "#" {
+		return s.root, nil
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported $ref %q: only local references are supported", ref)
+	}
+
+	node := s.root
+	for _, token := range strings.Split(ref[2:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		if node, ok = obj[token]; !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+	}
+	return node, nil
+}
+
+func asList(v interface{}) []interface{} {
+	list, _ := v.([]interface{})
+	return list
+}
+
+func number(v interface{}) (float64, bool) {
+	n, ok := v.(float64)
+	return n, ok
+}
+
+// jsonType names the JSON type of a decoded value
+func jsonType(v interface{}) string {
+	switch n := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if n == math.Trunc(n) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// matchesType checks value against a "type" keyword, which may be a list
+func matchesType(t interface{}, value interface{}) bool {
+	actual := jsonType(value)
+	for _, want := range typeNames(t) {
+		if want == actual || (want == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func typeNames(t interface{}) []string {
+	switch tt := t.(type) {
+	case string:
+		return []string{tt}
+	case []interface{}:
+		var names []string
+		for _, n := range tt {
+			if s, ok := n.(string); ok {
+				names = append(names, s)
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+func describeType(t interface{}) string {
+	return strings.Join(typeNames(t), " or ")
+}
+
+func equal(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func compact(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
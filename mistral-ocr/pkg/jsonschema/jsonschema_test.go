@@ -0,0 +1,193 @@
+package jsonschema
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		wantErr bool
+	}{
+		{name: "object", schema: `{"type": "object"}`},
+		{name: "boolean", schema: `true`},
+		{name: "invalid JSON", schema: `{"type":`, wantErr: true},
+		{name: "array", schema: `[]`, wantErr: true},
+		{name: "string", schema: `"object"`, wantErr: true},
+		{name: "invalid pattern", schema: `{"properties": {"a": {"pattern": "("}}}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile([]byte(tt.schema))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Compile error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+const invoiceSchema = `{
+	"$defs": {
+		"amount": {"type": "number", "minimum": 0, "multipleOf": 0.01}
+	},
+	"type": "object",
+	"required": ["number", "total", "lines"],
+	"additionalProperties": false,
+	"properties": {
+		"number": {"type": "string", "pattern": "^INV-[0-9]+$"},
+		"currency": {"enum": ["EUR", "USD"]},
+		"total": {"$ref": "#/$defs/amount"},
+		"paid": {"type": ["boolean", "null"]},
+		"tags": {"type": "array", "items": {"type": "string", "minLength": 1}, "uniqueItems": true, "maxItems": 3},
+		"lines": {
+			"type": "array",
+			"minItems": 1,
+			"items": {
+				"type": "object",
+				"required": ["description"],
+				"properties": {
+					"description": {"type": "string", "maxLength": 20},
+					"quantity": {"type": "integer", "exclusiveMinimum": 0}
+				}
+			}
+		},
+		"payer": {
+			"oneOf": [
+				{"type": "object", "required": ["iban"]},
+				{"type": "object", "required": ["card"]}
+			]
+		}
+	}
+}`
+
+func TestValidate(t *testing.T) {
+	schema, err := Compile([]byte(invoiceSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		doc  string
+		// problems are substrings expected in the error, none for a valid document
+		problems []string
+	}{
+		{
+			name: "valid",
+			doc:  `{"number": "INV-42", "currency": "EUR", "total": 12.5, "paid": null, "tags": ["a", "b"], "lines": [{"description": "Pens", "quantity": 3}], "payer": {"iban": "DE00"}}`,
+		},
+		{
+			name:     "missing required",
+			doc:      `{"number": "INV-1", "lines": [{"description": "x"}]}`,
+			problems: []string{`$: missing required property "total"`},
+		},
+		{
+			name:     "wrong type",
+			doc:      `{"number": 7, "total": 1, "lines": [{"description": "x"}]}`,
+			problems: []string{"$.number: expected string, got integer"},
+		},
+		{
+			name:     "pattern",
+			doc:      `{"number": "42", "total": 1, "lines": [{"description": "x"}]}`,
+			problems: []string{"$.number: string does not match pattern"},
+		},
+		{
+			name:     "enum",
+			doc:      `{"number": "INV-1", "currency": "GBP", "total": 1, "lines": [{"description": "x"}]}`,
+			problems: []string{`$.currency: value "GBP" is not one of the allowed values`},
+		},
+		{
+			name:     "ref with bounds",
+			doc:      `{"number": "INV-1", "total": -1.001, "lines": [{"description": "x"}]}`,
+			problems: []string{"$.total: -1.001 is less than minimum 0", "$.total: -1.001 is not a multiple of 0.01"},
+		},
+		{
+			name:     "additional property",
+			doc:      `{"number": "INV-1", "total": 1, "lines": [{"description": "x"}], "extra": 1}`,
+			problems: []string{`$: unexpected property "extra"`},
+		},
+		{
+			name:     "nested items",
+			doc:      `{"number": "INV-1", "total": 1, "lines": [{"description": "a much too long description"}, {"quantity": 0}]}`,
+			problems: []string{"$.lines[0].description: string longer than 20 characters", `$.lines[1]: missing required property "description"`, "$.lines[1].quantity: 0 must be greater than 0"},
+		},
+		{
+			name:     "integer",
+			doc:      `{"number": "INV-1", "total": 1, "lines": [{"description": "x", "quantity": 1.5}]}`,
+			problems: []string{"$.lines[0].quantity: expected integer, got number"},
+		},
+		{
+			name:     "array bounds",
+			doc:      `{"number": "INV-1", "total": 1, "lines": [], "tags": ["a", "a", "", "b"]}`,
+			problems: []string{"$.lines: array has fewer than 1 items", "$.tags: array has more than 3 items", "$.tags: array items 0 and 1 are equal", "$.tags[2]: string shorter than 1 characters"},
+		},
+		{
+			name:     "oneOf",
+			doc:      `{"number": "INV-1", "total": 1, "lines": [{"description": "x"}], "payer": {"iban": "x", "card": "y"}}`,
+			problems: []string{"$.payer: value must match exactly one schema, matched 2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.ValidateJSON([]byte(tt.doc))
+			if len(tt.problems) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("expected a *ValidationError, got %v", err)
+			}
+			if len(verr.Problems) != len(tt.problems) {
+				t.Errorf("got %d problems %q, want %d", len(verr.Problems), verr.Problems, len(tt.problems))
+			}
+			for _, want := range tt.problems {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateSelfReference(t *testing.T) {
+	schema, err := Compile([]byte(`{
+		"definitions": {"node": {"type": "object", "properties": {"child": {"$ref": "#/definitions/node"}}}},
+		"$ref": "#/definitions/node"
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := schema.ValidateJSON([]byte(`{"child": {"child": {}}}`)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := schema.ValidateJSON([]byte(`{"child": {"child": 1}}`)); err == nil {
+		t.Error("expected an error for a non-object child")
+	}
+}
+
+func TestValidateBooleanSchemas(t *testing.T) {
+	schema, err := Compile([]byte(`{"properties": {"never": false, "any": true}, "not": {"required": ["forbidden"]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := schema.ValidateJSON([]byte(`{"any": [1, "x"]}`)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := schema.ValidateJSON([]byte(`{"never": 1}`)); err == nil {
+		t.Error("expected an error for a property with a false schema")
+	}
+	if err := schema.ValidateJSON([]byte(`{"forbidden": 1}`)); err == nil {
+		t.Error("expected an error for a document matching not")
+	}
+}
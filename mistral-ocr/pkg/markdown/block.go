@@ -0,0 +1,373 @@
+// Package markdown parses the Markdown produced by Mistral OCR (CommonMark
+// with GitHub tables and TeX math) into blocks that can be rendered to other
+// formats or split without breaking tables and code fences.
+package markdown
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// BlockKind identifies the type of a Block
+type BlockKind int
+
+const (
+	Paragraph BlockKind = iota
+	Heading
+	CodeBlock
+	MathBlock
+	Table
+	List
+	Quote
+	Rule
+)
+
+// Block is a block level element of a Markdown document
+type Block struct {
+	Kind BlockKind
+	// Level is the heading level (1-6)
+	Level int
+	// Text holds the inline Markdown of paragraphs and headings and the
+	// literal content of code and math blocks
+	Text string
+	// Lang is the info string of a fenced code block
+	Lang string
+	// Rows holds the cells of a table, the header row first
+	Rows [][]string
+	// Align holds the alignment of each table column: "left", "center", "right" or ""
+	Align []string
+	// Ordered and Start describe lists; Items holds the blocks of each list item
+	Ordered bool
+	Start   int
+	Items   [][]Block
+	// Children holds the content of a block quote
+	Children []Block
+	// ID is an optional anchor rendered on headings
+	ID string
+	// Source is the Markdown the block was parsed from
+	Source string
+}
+
+var (
+	atxHeading    = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	fenceOpen     = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})[ \t]*([^`]*)$")
+	thematicBreak = regexp.MustCompile(`^ {0,3}((\*[ \t]*){3,}|(-[ \t]*){3,}|(_[ \t]*){3,})$`)
+	listMarker    = regexp.MustCompile(`^( {0,3})([*+-]|(\d{1,9})[.)])([ \t]+|$)`)
+	tableDelim    = regexp.MustCompile(`^ {0,3}\|?[ \t]*:?-+:?[ \t]*(\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+	setextLine    = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+)
+
+// Parse splits src into blocks
+func Parse(src string) []Block {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	return parseLines(lines)
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func indentOf(line string) int {
+	n := 0
+	for _, c := range line {
+		switch c {
+		case ' ':
+			n++
+		case '\t':
+			n += 4 - n%4
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+// stripIndent removes up to n columns of leading whitespace
+func stripIndent(line string, n int) string {
+	col := 0
+	for i, c := range line {
+		if col >= n {
+			return line[i:]
+		}
+		switch c {
+		case ' ':
+			col++
+		case '\t':
+			col += 4 - col%4
+		default:
+			return line[i:]
+		}
+	}
+	return ""
+}
+
+func isTableStart(lines []string, i int) bool {
+	return i+1 < len(lines) && strings.Contains(lines[i], "|") && tableDelim.MatchString(lines[i+1]) &&
+		len(splitRow(lines[i])) == len(splitRow(lines[i+1]))
+}
+
+// startsBlock reports whether line i begins a block that interrupts a paragraph
+func startsBlock(lines []string, i int) bool {
+	line := lines[i]
+	trimmed := strings.TrimSpace(line)
+	return atxHeading.MatchString(line) || fenceOpen.MatchString(line) || thematicBreak.MatchString(line) ||
+		strings.HasPrefix(trimmed, ">") || strings.HasPrefix(trimmed, "$$") || strings.HasPrefix(trimmed, `\[`) ||
+		isTableStart(lines, i) || (listMarker.MatchString(line) && !isBlank(listMarker.ReplaceAllString(line, "")))
+}
+
+func parseLines(lines []string) []Block {
+	var blocks []Block
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		if isBlank(line) {
+			i++
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		start := i
+
+		switch {
+		case fenceOpen.MatchString(line):
+			m := fenceOpen.FindStringSubmatch(line)
+			indent, fence := len(m[1]), m[2]
+			var content []string
+			i++
+			for i < len(lines) {
+				t := strings.TrimSpace(lines[i])
+				if strings.HasPrefix(t, fence[:1]) && len(strings.TrimRight(t, fence[:1])) == 0 && len(t) >= len(fence) {
+					i++
+					break
+				}
+				content = append(content, stripIndent(lines[i], indent))
+				i++
+			}
+			blocks = append(blocks, Block{Kind: CodeBlock, Lang: strings.TrimSpace(m[3]), Text: strings.Join(content, "\n")})
+
+		case indentOf(line) >= 4:
+			var content []string
+			for i < len(lines) && (isBlank(lines[i]) || indentOf(lines[i]) >= 4) {
+				content = append(content, stripIndent(lines[i], 4))
+				i++
+			}
+			for len(content) > 0 && isBlank(content[len(content)-1]) {
+				content = content[:len(content)-1]
+				i--
+			}
+			blocks = append(blocks, Block{Kind: CodeBlock, Text: strings.Join(content, "\n")})
+
+		case strings.HasPrefix(trimmed, "$$") || strings.HasPrefix(trimmed, `\[`):
+			open, close := "$$", "$$"
+			if strings.HasPrefix(trimmed, `\[`) {
+				open, close = `\[`, `\]`
+			}
+			rest := strings.TrimPrefix(trimmed, open)
+			var content []string
+			if j := strings.Index(rest, close); j >= 0 {
+				content = append(content, rest[:j])
+				i++
+			} else {
+				content = append(content, rest)
+				i++
+				for i < len(lines) {
+					t := strings.TrimSpace(lines[i])
+					i++
+					if j := strings.Index(t, close); j >= 0 {
+						content = append(content, t[:j])
+						break
+					}
+					content = append(content, lines[i-1])
+				}
+			}
+			blocks = append(blocks, Block{Kind: MathBlock, Text: strings.TrimSpace(strings.Join(content, "\n"))})
+
+		case atxHeading.MatchString(line):
+			m := atxHeading.FindStringSubmatch(line)
+			blocks = append(blocks, Block{Kind: Heading, Level: len(m[1]), Text: strings.TrimSpace(m[2])})
+			i++
+
+		case thematicBreak.MatchString(line):
+			blocks = append(blocks, Block{Kind: Rule})
+			i++
+
+		case isTableStart(lines, i):
+			b := Block{Kind: Table}
+			b.Rows = append(b.Rows, splitRow(lines[i]))
+			for _, cell := range splitRow(lines[i+1]) {
+				b.Align = append(b.Align, cellAlign(cell))
+			}
+			i += 2
+			for i < len(lines) && !isBlank(lines[i]) && strings.Contains(lines[i], "|") {
+				b.Rows = append(b.Rows, splitRow(lines[i]))
+				i++
+			}
+			blocks = append(blocks, b)
+
+		case strings.HasPrefix(trimmed, ">"):
+			var inner []string
+			for i < len(lines) && !isBlank(lines[i]) {
+				t := strings.TrimLeft(lines[i], " ")
+				if strings.HasPrefix(t, ">") {
+					t = strings.TrimPrefix(strings.TrimPrefix(t, ">"), " ")
+				} else if startsBlock(lines, i) {
+					break
+				}
+				inner = append(inner, t)
+				i++
+			}
+			blocks = append(blocks, Block{Kind: Quote, Children: parseLines(inner)})
+
+		case listMarker.MatchString(line):
+			var b Block
+			b, i = parseList(lines, i)
+			blocks = append(blocks, b)
+
+		default:
+			para := []string{trimmed}
+			i++
+			heading := 0
+			for i < len(lines) && !isBlank(lines[i]) {
+				if m := setextLine.FindStringSubmatch(lines[i]); m != nil {
+					heading = 2
+					if m[1][0] == '=' {
+						heading = 1
+					}
+					i++
+					break
+				}
+				if startsBlock(lines, i) {
+					break
+				}
+				para = append(para, strings.TrimSpace(lines[i]))
+				i++
+			}
+			b := Block{Kind: Paragraph, Text: strings.Join(para, "\n")}
+			if heading > 0 {
+				b.Kind = Heading
+				b.Level = heading
+			}
+			blocks = append(blocks, b)
+		}
+
+		blocks[len(blocks)-1].Source = strings.TrimRight(strings.Join(lines[start:i], "\n"), "\n")
+	}
+
+	return blocks
+}
+
+// parseList reads a list starting at line i and returns it with the index of the next line
+func parseList(lines []string, i int) (Block, int) {
+	m := listMarker.FindStringSubmatch(lines[i])
+	b := Block{Kind: List, Ordered: m[3] != ""}
+	if b.Ordered {
+		b.Start, _ = strconv.Atoi(m[3])
+	}
+	markerType := m[2][len(m[2])-1:]
+
+	var item []string
+	flush := func() {
+		if item != nil {
+			b.Items = append(b.Items, parseLines(item))
+		}
+		item = nil
+	}
+
+	// contentIndent is the column the content of the current item starts at,
+	// the marker's own indent plus its width
+	contentIndent := 0
+	first := true
+	blankBefore := false
+	for i < len(lines) {
+		line := lines[i]
+
+		if isBlank(line) {
+			blankBefore = true
+			item = append(item, "")
+			i++
+			continue
+		}
+
+		// Markers indented less than the current item's content start a sibling item
+		if m := listMarker.FindStringSubmatch(line); m != nil && (first || indentOf(line) < contentIndent) {
+			if m[2][len(m[2])-1:] != markerType || (m[3] != "") != b.Ordered {
+				break
+			}
+			flush()
+			width := len(m[0])
+			if len(m[4]) > 4 {
+				// Content indented by more than four spaces starts with an indented code block
+				width = len(m[1]) + len(m[2]) + 1
+			}
+			contentIndent = indentOf(m[1]) + width - len(m[1])
+			item = append(item, line[len(m[0]):])
+			first = false
+			blankBefore = false
+			i++
+			continue
+		}
+
+		if indentOf(line) >= contentIndent {
+			item = append(item, stripIndent(line, contentIndent))
+		} else if !blankBefore && !startsBlock(lines, i) {
+			// Lazy continuation of the item's paragraph
+			item = append(item, strings.TrimSpace(line))
+		} else {
+			break
+		}
+		blankBefore = false
+		i++
+	}
+	flush()
+
+	// Trailing blank lines belong to whatever follows the list
+	for i > 0 && isBlank(lines[i-1]) {
+		i--
+	}
+	return b, i
+}
+
+// splitRow splits a table row into trimmed cells, honouring escaped pipes
+// and pipes inside code spans
+func splitRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+
+	var cells []string
+	var cell strings.Builder
+	inCode := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case c == '`':
+			inCode = !inCode
+			cell.WriteByte(c)
+		case c == '|' && !inCode:
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(c)
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+func cellAlign(delim string) string {
+	left := strings.HasPrefix(delim, ":")
+	right := strings.HasSuffix(delim, ":")
+	switch {
+	case left && right:
+		return "center"
+	case right:
+		return "right"
+	case left:
+		return "left"
+	}
+	return ""
+}
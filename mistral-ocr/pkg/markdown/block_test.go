@@ -0,0 +1,73 @@
+package markdown
+
+import (
+	"reflect"
+	"testing"
+)
+
+// listText returns the paragraph text of each item of a list, with the
+// items of nested lists in a slice after their parent's text
+func listText(b Block) []any {
+	var items []any
+	for _, item := range b.Items {
+		for _, child := range item {
+			switch child.Kind {
+			case Paragraph:
+				items = append(items, child.Text)
+			case List:
+				items = append(items, listText(child))
+			}
+		}
+	}
+	return items
+}
+
+func TestParseList(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []any
+	}{
+		{name: "flush", src: "- first\n- second", want: []any{"first", "second"}},
+		{name: "indented", src: "  - item\n  - two\n", want: []any{"item", "two"}},
+		{name: "indented three spaces", src: "   1. one\n   2. two", want: []any{"one", "two"}},
+		{name: "outdented sibling", src: "- a\n  - b\n - c", want: []any{"a", []any{"b"}, "c"}},
+		{name: "nested", src: "- a\n  - b\n  - c\n- d", want: []any{"a", []any{"b", "c"}, "d"}},
+		{name: "nested under indented", src: " - a\n   - b\n - c", want: []any{"a", []any{"b"}, "c"}},
+		{name: "continuation", src: "- first\n  more\n- second", want: []any{"first\nmore", "second"}},
+		{name: "lazy continuation", src: "  - first\nstill first\n  - second", want: []any{"first\nstill first", "second"}},
+		{name: "empty item", src: "-\n- b", want: []any{"b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := Parse(tt.src)
+			if len(blocks) != 1 || blocks[0].Kind != List {
+				t.Fatalf("got %d blocks %+v, want one list", len(blocks), blocks)
+			}
+			if got := listText(blocks[0]); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("items = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseIndentedListAfterParagraph(t *testing.T) {
+	blocks := Parse("Items:\n\n  - first\n  - second\n\nAfter")
+	if len(blocks) != 3 {
+		t.Fatalf("got %d blocks %+v, want 3", len(blocks), blocks)
+	}
+	if blocks[0].Kind != Paragraph || blocks[2].Kind != Paragraph || blocks[2].Text != "After" {
+		t.Errorf("unexpected blocks around the list: %+v", blocks)
+	}
+	if got, want := listText(blocks[1]), []any{"first", "second"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("items = %q, want %q", got, want)
+	}
+}
+
+func TestParseListEndsAtOtherMarker(t *testing.T) {
+	blocks := Parse("- a\n- b\n1. one")
+	if len(blocks) != 2 || blocks[0].Kind != List || blocks[1].Kind != List || !blocks[1].Ordered {
+		t.Fatalf("got %+v, want a bullet list followed by an ordered list", blocks)
+	}
+}
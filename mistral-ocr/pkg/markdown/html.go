@@ -0,0 +1,191 @@
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// HTML renders blocks as an HTML fragment. Math is wrapped in
+// class="math" elements using \( \) and \[ \] delimiters, which KaTeX's
+// auto-render extension (and MathJax) pick up.
+func HTML(blocks []Block) string {
+	var b strings.Builder
+	writeBlocksHTML(&b, blocks, false)
+	return b.String()
+}
+
+// InlineHTML renders a single line of inline Markdown as HTML
+func InlineHTML(text string) string {
+	var b strings.Builder
+	writeInlineHTML(&b, parseInline(text))
+	return b.String()
+}
+
+func writeBlocksHTML(b *strings.Builder, blocks []Block, tight bool) {
+	for _, block := range blocks {
+		switch block.Kind {
+		case Paragraph:
+			if tight {
+				writeInlineHTML(b, parseInline(block.Text))
+				b.WriteString("\n")
+				continue
+			}
+			b.WriteString("<p>")
+			writeInlineHTML(b, parseInline(block.Text))
+			b.WriteString("</p>\n")
+
+		case Heading:
+			if block.ID != "" {
+				fmt.Fprintf(b, "<h%d id=\"%s\">", block.Level, html.EscapeString(block.ID))
+			} else {
+				fmt.Fprintf(b, "<h%d>", block.Level)
+			}
+			writeInlineHTML(b, parseInline(block.Text))
+			fmt.Fprintf(b, "</h%d>\n", block.Level)
+
+		case CodeBlock:
+			b.WriteString("<pre><code")
+			if block.Lang != "" {
+				lang := strings.Fields(block.Lang)[0]
+				fmt.Fprintf(b, " class=\"language-%s\"", html.EscapeString(lang))
+			}
+			b.WriteString(">")
+			b.WriteString(html.EscapeString(block.Text))
+			b.WriteString("\n</code></pre>\n")
+
+		case MathBlock:
+			fmt.Fprintf(b, "<div class=\"math display\">\\[%s\\]</div>\n", html.EscapeString(block.Text))
+
+		case Table:
+			writeTableHTML(b, block)
+
+		case List:
+			tag := "ul"
+			if block.Ordered {
+				tag = "ol"
+			}
+			if block.Ordered && block.Start != 1 {
+				fmt.Fprintf(b, "<%s start=\"%d\">\n", tag, block.Start)
+			} else {
+				fmt.Fprintf(b, "<%s>\n", tag)
+			}
+			for _, item := range block.Items {
+				b.WriteString("<li>")
+				// Items holding a single paragraph are rendered without <p>
+				writeBlocksHTML(b, item, len(item) > 0 && item[0].Kind == Paragraph && (len(item) == 1 || item[1].Kind == List))
+				b.WriteString("</li>\n")
+			}
+			fmt.Fprintf(b, "</%s>\n", tag)
+
+		case Quote:
+			b.WriteString("<blockquote>\n")
+			writeBlocksHTML(b, block.Children, false)
+			b.WriteString("</blockquote>\n")
+
+		case Rule:
+			b.WriteString("<hr>\n")
+		}
+	}
+}
+
+func writeTableHTML(b *strings.Builder, block Block) {
+	cell := func(tag string, col int, text string) {
+		if col < len(block.Align) && block.Align[col] != "" {
+			fmt.Fprintf(b, "<%s style=\"text-align: %s\">", tag, block.Align[col])
+		} else {
+			fmt.Fprintf(b, "<%s>", tag)
+		}
+		writeInlineHTML(b, parseInline(text))
+		fmt.Fprintf(b, "</%s>", tag)
+	}
+
+	columns := len(block.Align)
+	b.WriteString("<table>\n<thead>\n<tr>")
+	for col, text := range block.Rows[0] {
+		cell("th", col, text)
+	}
+	b.WriteString("</tr>\n</thead>\n")
+
+	if len(block.Rows) > 1 {
+		b.WriteString("<tbody>\n")
+		for _, row := range block.Rows[1:] {
+			b.WriteString("<tr>")
+			// Rows are padded or cut to the header's column count
+			for col := 0; col < columns; col++ {
+				text := ""
+				if col < len(row) {
+					text = row[col]
+				}
+				cell("td", col, text)
+			}
+			b.WriteString("</tr>\n")
+		}
+		b.WriteString("</tbody>\n")
+	}
+	b.WriteString("</table>\n")
+}
+
+func writeInlineHTML(b *strings.Builder, nodes []inline) {
+	for _, n := range nodes {
+		switch n.kind {
+		case textNode:
+			b.WriteString(html.EscapeString(n.text))
+		case codeNode:
+			b.WriteString("<code>" + html.EscapeString(n.text) + "</code>")
+		case mathNode:
+			b.WriteString(`<span class="math inline">\(` + html.EscapeString(n.text) + `\)</span>`)
+		case displayMathNode:
+			b.WriteString(`<span class="math display">\[` + html.EscapeString(n.text) + `\]</span>`)
+		case emphasisNode:
+			b.WriteString("<em>")
+			writeInlineHTML(b, n.children)
+			b.WriteString("</em>")
+		case strongNode:
+			b.WriteString("<strong>")
+			writeInlineHTML(b, n.children)
+			b.WriteString("</strong>")
+		case strikeNode:
+			b.WriteString("<del>")
+			writeInlineHTML(b, n.children)
+			b.WriteString("</del>")
+		case linkNode:
+			href := "#"
+			if safeURL(n.url, false) {
+				href = n.url
+			}
+			fmt.Fprintf(b, "<a href=\"%s\">", html.EscapeString(href))
+			writeInlineHTML(b, n.children)
+			b.WriteString("</a>")
+		case imageNode:
+			if safeURL(n.url, true) {
+				fmt.Fprintf(b, "<img src=\"%s\" alt=\"%s\">", html.EscapeString(n.url), html.EscapeString(n.text))
+			} else {
+				fmt.Fprintf(b, "<img alt=\"%s\">", html.EscapeString(n.text))
+			}
+		case breakNode:
+			b.WriteString("<br>\n")
+		}
+	}
+}
+
+// safeURL reports whether url can be rendered as a link or image source:
+// relative URLs, http, https and mailto, and data:image/ URLs for images.
+// Anything else, like javascript:, could run script in the rendered page.
+func safeURL(url string, image bool) bool {
+	url = strings.TrimSpace(url)
+	end := strings.IndexAny(url, ":/?#")
+	if end < 0 || url[end] != ':' {
+		return true
+	}
+	scheme := strings.ToLower(url[:end])
+	switch scheme {
+	case "http", "https":
+		return true
+	case "mailto":
+		return !image
+	case "data":
+		return image && strings.HasPrefix(strings.ToLower(url[end+1:]), "image/")
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInlineHTMLURLs(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{src: "[a](https://example.com/x?y=1&z=2)", want: `<a href="https://example.com/x?y=1&amp;z=2">a</a>`},
+		{src: "[a](HTTP://example.com)", want: `<a href="HTTP://example.com">a</a>`},
+		{src: "[a](mailto:me@example.com)", want: `<a href="mailto:me@example.com">a</a>`},
+		{src: "[a](#section)", want: `<a href="#section">a</a>`},
+		{src: "[a](img/page-1.png)", want: `<a href="img/page-1.png">a</a>`},
+		{src: "[a](javascript:alert%281%29)", want: `<a href="#">a</a>`},
+		{src: "[a](JavaScript:alert%281%29)", want: `<a href="#">a</a>`},
+		{src: "[a](vbscript:x)", want: `<a href="#">a</a>`},
+		{src: "[a](data:text/html;base64,PHNjcmlwdD4=)", want: `<a href="#">a</a>`},
+		{src: "![p](img-0.jpeg)", want: `<img src="img-0.jpeg" alt="p">`},
+		{src: "![p](data:image/png;base64,iVBORw0=)", want: `<img src="data:image/png;base64,iVBORw0=" alt="p">`},
+		{src: "![p](javascript:alert%281%29)", want: `<img alt="p">`},
+		{src: "![p](data:text/html,x)", want: `<img alt="p">`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			if got := InlineHTML(tt.src); got != tt.want {
+				t.Errorf("InlineHTML(%q) = %q, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTMLEscapesText(t *testing.T) {
+	got := HTML(Parse("<script>alert(1)</script>"))
+	if strings.Contains(got, "<script>") {
+		t.Errorf("HTML did not escape raw markup: %q", got)
+	}
+}
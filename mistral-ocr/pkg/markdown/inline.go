@@ -0,0 +1,303 @@
+package markdown
+
+import (
+	"strings"
+)
+
+type inlineKind int
+
+const (
+	textNode inlineKind = iota
+	codeNode
+	mathNode
+	displayMathNode
+	emphasisNode
+	strongNode
+	strikeNode
+	linkNode
+	imageNode
+	breakNode
+)
+
+// inline is a span level element of a paragraph, heading or table cell
+type inline struct {
+	kind     inlineKind
+	text     string
+	url      string
+	children []inline
+}
+
+const punctuation = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+func isAlnum(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// parseInline splits text into inline elements
+func parseInline(s string) []inline {
+	var nodes []inline
+	var buf strings.Builder
+
+	flush := func() {
+		if buf.Len() > 0 {
+			nodes = append(nodes, inline{kind: textNode, text: buf.String()})
+			buf.Reset()
+		}
+	}
+	emit := func(n inline) {
+		flush()
+		nodes = append(nodes, n)
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && (s[i+1] == '(' || s[i+1] == '['):
+			close := `\)`
+			kind := mathNode
+			if s[i+1] == '[' {
+				close, kind = `\]`, displayMathNode
+			}
+			if j := strings.Index(s[i+2:], close); j >= 0 {
+				emit(inline{kind: kind, text: strings.TrimSpace(s[i+2 : i+2+j])})
+				i += j + 4
+				continue
+			}
+			buf.WriteByte(s[i+1])
+			i += 2
+
+		case c == '\\' && i+1 < len(s) && s[i+1] == '\n':
+			emit(inline{kind: breakNode})
+			i += 2
+
+		case c == '\\' && i+1 < len(s) && strings.IndexByte(punctuation, s[i+1]) >= 0:
+			buf.WriteByte(s[i+1])
+			i += 2
+
+		case c == '`':
+			n := runLength(s, i, '`')
+			if j := findRun(s, i+n, '`', n); j >= 0 {
+				code := strings.ReplaceAll(s[i+n:j], "\n", " ")
+				if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+					code = code[1 : len(code)-1]
+				}
+				emit(inline{kind: codeNode, text: code})
+				i = j + n
+				continue
+			}
+			buf.WriteString(s[i : i+n])
+			i += n
+
+		case c == '$':
+			if end, text, display, ok := scanMath(s, i); ok {
+				kind := mathNode
+				if display {
+					kind = displayMathNode
+				}
+				emit(inline{kind: kind, text: text})
+				i = end
+				continue
+			}
+			buf.WriteByte(c)
+			i++
+
+		case c == '!' && i+1 < len(s) && s[i+1] == '[':
+			if end, label, url, ok := scanLink(s, i+1); ok {
+				emit(inline{kind: imageNode, text: label, url: url})
+				i = end
+				continue
+			}
+			buf.WriteByte(c)
+			i++
+
+		case c == '[':
+			if end, label, url, ok := scanLink(s, i); ok {
+				emit(inline{kind: linkNode, url: url, children: parseInline(label)})
+				i = end
+				continue
+			}
+			buf.WriteByte(c)
+			i++
+
+		case c == '<':
+			if j := strings.IndexByte(s[i:], '>'); j > 0 {
+				target := s[i+1 : i+j]
+				if !strings.ContainsAny(target, " \t\n<") && (strings.Contains(target, "://") || strings.HasPrefix(target, "mailto:")) {
+					emit(inline{kind: linkNode, url: target, children: []inline{{kind: textNode, text: target}}})
+					i += j + 1
+					continue
+				}
+			}
+			buf.WriteByte(c)
+			i++
+
+		case c == '*' || c == '_' || c == '~':
+			n := runLength(s, i, c)
+			if end, node, ok := scanEmphasis(s, i, c, n); ok {
+				emit(node)
+				i = end
+				continue
+			}
+			buf.WriteString(s[i : i+n])
+			i += n
+
+		case c == '\n':
+			text := buf.String()
+			if strings.HasSuffix(text, "  ") {
+				buf.Reset()
+				buf.WriteString(strings.TrimRight(text, " "))
+				emit(inline{kind: breakNode})
+			} else {
+				buf.WriteByte(c)
+			}
+			i++
+
+		default:
+			buf.WriteByte(c)
+			i++
+		}
+	}
+	flush()
+
+	return nodes
+}
+
+func runLength(s string, i int, c byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == c {
+		n++
+	}
+	return n
+}
+
+// findRun returns the index of the next run of exactly n c characters at or after i
+func findRun(s string, i int, c byte, n int) int {
+	for i < len(s) {
+		j := strings.IndexByte(s[i:], c)
+		if j < 0 {
+			return -1
+		}
+		j += i
+		if l := runLength(s, j, c); l == n {
+			return j
+		} else {
+			i = j + l
+		}
+	}
+	return -1
+}
+
+// scanMath recognises $...$ and $$...$$. Like pandoc, an inline opening $
+// must be followed by a non-space and the closing $ must follow a non-space
+// and not precede a digit. A $ that cannot close the span ends the attempt,
+// so amounts such as "$5 and $10" stay text.
+func scanMath(s string, i int) (end int, text string, display bool, ok bool) {
+	if strings.HasPrefix(s[i:], "$$") {
+		if j := strings.Index(s[i+2:], "$$"); j > 0 {
+			return i + j + 4, strings.TrimSpace(s[i+2 : i+2+j]), true, true
+		}
+		return 0, "", false, false
+	}
+
+	if i+1 >= len(s) || isSpace(s[i+1]) {
+		return 0, "", false, false
+	}
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '$':
+			if !isSpace(s[j-1]) && (j+1 >= len(s) || s[j+1] < '0' || s[j+1] > '9') {
+				return j + 1, s[i+1 : j], false, true
+			}
+			return 0, "", false, false
+		}
+	}
+	return 0, "", false, false
+}
+
+// scanLink parses [label](url "title") starting at the opening bracket
+func scanLink(s string, i int) (end int, label, url string, ok bool) {
+	depth := 0
+	j := i
+	for ; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+			continue
+		case '[':
+			depth++
+		case ']':
+			depth--
+		}
+		if depth == 0 {
+			break
+		}
+	}
+	if j >= len(s) || j+1 >= len(s) || s[j+1] != '(' {
+		return 0, "", "", false
+	}
+	label = s[i+1 : j]
+
+	k := strings.IndexByte(s[j+2:], ')')
+	if k < 0 {
+		return 0, "", "", false
+	}
+	dest := strings.TrimSpace(s[j+2 : j+2+k])
+	if strings.HasPrefix(dest, "<") {
+		if e := strings.IndexByte(dest, '>'); e > 0 {
+			dest = dest[1:e]
+		}
+	} else if sp := strings.IndexAny(dest, " \t\n"); sp >= 0 {
+		// Drop the optional title
+		dest = dest[:sp]
+	}
+	return j + 3 + k, label, dest, true
+}
+
+// scanEmphasis parses *em*, **strong**, ***both***, the underscore forms and ~~strike~~
+func scanEmphasis(s string, i int, c byte, n int) (int, inline, bool) {
+	if i+n >= len(s) || isSpace(s[i+n]) {
+		return 0, inline{}, false
+	}
+	if c == '_' && i > 0 && isAlnum(s[i-1]) {
+		// Intraword underscores, as in snake_case, are literal
+		return 0, inline{}, false
+	}
+	if c == '~' && n != 2 {
+		return 0, inline{}, false
+	}
+	if n > 3 {
+		return 0, inline{}, false
+	}
+
+	for j := i + n; j < len(s); {
+		k := strings.IndexByte(s[j:], c)
+		if k < 0 {
+			break
+		}
+		k += j
+		l := runLength(s, k, c)
+		if l == n && !isSpace(s[k-1]) && !(c == '_' && k+n < len(s) && isAlnum(s[k+n])) {
+			children := parseInline(s[i+n : k])
+			var node inline
+			switch {
+			case c == '~':
+				node = inline{kind: strikeNode, children: children}
+			case n == 1:
+				node = inline{kind: emphasisNode, children: children}
+			case n == 2:
+				node = inline{kind: strongNode, children: children}
+			default:
+				node = inline{kind: strongNode, children: []inline{{kind: emphasisNode, children: children}}}
+			}
+			return k + n, node, true
+		}
+		j = k + l
+	}
+	return 0, inline{}, false
+}
@@ -0,0 +1,83 @@
+package markdown
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Text renders blocks as plain text: headings become plain lines, table rows
+// are joined with tabs, images are dropped and emphasis, link and code
+// markers are removed. Math is kept as its TeX source.
+func Text(blocks []Block) string {
+	return joinText(blocks, "\n\n")
+}
+
+func joinText(blocks []Block, separator string) string {
+	var parts []string
+	for _, block := range blocks {
+		if text := blockText(block); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, separator)
+}
+
+// InlineText strips the Markdown syntax from a line of inline Markdown
+func InlineText(text string) string {
+	var b strings.Builder
+	writeInlineText(&b, parseInline(text))
+	return b.String()
+}
+
+func blockText(block Block) string {
+	switch block.Kind {
+	case Paragraph, Heading:
+		return strings.TrimSpace(InlineText(block.Text))
+
+	case CodeBlock, MathBlock:
+		return block.Text
+
+	case Table:
+		var rows []string
+		for _, row := range block.Rows {
+			cells := make([]string, len(row))
+			for i, cell := range row {
+				cells[i] = InlineText(cell)
+			}
+			rows = append(rows, strings.Join(cells, "\t"))
+		}
+		return strings.Join(rows, "\n")
+
+	case List:
+		var items []string
+		for i, item := range block.Items {
+			marker := "•"
+			if block.Ordered {
+				marker = strconv.Itoa(block.Start+i) + "."
+			}
+			// Item content is kept together on consecutive lines
+			text := joinText(item, "\n")
+			indent := strings.Repeat(" ", utf8.RuneCountInString(marker)+1)
+			items = append(items, marker+" "+strings.ReplaceAll(text, "\n", "\n"+indent))
+		}
+		return strings.Join(items, "\n")
+
+	case Quote:
+		return Text(block.Children)
+	}
+	return ""
+}
+
+func writeInlineText(b *strings.Builder, nodes []inline) {
+	for _, n := range nodes {
+		switch n.kind {
+		case textNode, codeNode, mathNode, displayMathNode:
+			b.WriteString(n.text)
+		case emphasisNode, strongNode, strikeNode, linkNode:
+			writeInlineText(b, n.children)
+		case breakNode:
+			b.WriteString("\n")
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package mistral
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DefaultChatModel is the chat model used when none is given
+const DefaultChatModel = "mistral-small-latest"
+
+// ChatMessage is a message of a chat conversation
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatRequest is the body of a chat completions request
+type ChatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []ChatMessage `json:"messages"`
+	Temperature *float64      `json:"temperature,omitempty"`
+	MaxTokens   int           `json:"max_tokens,omitempty"`
+	Stream      bool          `json:"stream,omitempty"`
+}
+
+// ChatResponse is the response of the chat completions endpoint
+type ChatResponse struct {
+	ID      string       `json:"id"`
+	Model   string       `json:"model"`
+	Choices []ChatChoice `json:"choices"`
+	Usage   Usage        `json:"usage"`
+}
+
+// ChatChoice is a completion returned for a chat request
+type ChatChoice struct {
+	Index        int         `json:"index"`
+	Message      ChatMessage `json:"message"`
+	FinishReason string      `json:"finish_reason"`
+}
+
+// chatChunk is an event of a streamed chat completion
+type chatChunk struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Index int `json:"index"`
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *Usage `json:"usage"`
+}
+
+// Chat returns the completion of req. It is retried like OCR requests.
+func (c *Client) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	requestBody := *req
+	requestBody.Stream = false
+	if requestBody.Model == "" {
+		requestBody.Model = DefaultChatModel
+	}
+
+	data, err := c.postJSON(ctx, "/chat/completions", requestBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp ChatResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("error parsing chat response: %v", err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("API returned no choices")
+	}
+	return &resp, nil
+}
+
+// ChatStream streams the completion of req, calling onDelta with every piece
+// of the answer as it arrives, and returns the assembled response. Requests
+// that fail before the first piece arrives are retried by the retry policy.
+func (c *Client) ChatStream(ctx context.Context, req *ChatRequest, onDelta func(string)) (*ChatResponse, error) {
+	requestBody := *req
+	requestBody.Stream = true
+	if requestBody.Model == "" {
+		requestBody.Model = DefaultChatModel
+	}
+
+	var result *ChatResponse
+	err := c.retry(ctx, func() error {
+		release, err := c.limits.other.acquire(ctx)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		resp, err := c.client.R().
+			SetContext(ctx).
+			SetHeader("Content-Type", "application/json").
+			SetHeader("Authorization", "Bearer "+c.APIKey).
+			SetHeader("Accept", "text/event-stream").
+			SetBody(requestBody).
+			SetDoNotParseResponse(true).
+			Post("/chat/completions")
+
+		if err != nil {
+			return sendError(err, "error making request: %w")
+		}
+
+		body := resp.RawBody()
+		defer body.Close()
+		if resp.StatusCode() != 200 {
+			errBody, _ := io.ReadAll(io.LimitReader(body, 64*1024))
+			return c.apiError(resp.StatusCode(), resp.Header(), errBody)
+		}
+
+		var received bool
+		result, received, err = readChatStream(body, onDelta)
+		if err != nil && !received {
+			return &retryError{err: err}
+		}
+		// Part of the answer may already have been passed on, so it cannot be retried
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// readChatStream reads server-sent events until [DONE], reporting whether
+// any content was passed to onDelta
+func readChatStream(r io.Reader, onDelta func(string)) (*ChatResponse, bool, error) {
+	result := &ChatResponse{Choices: []ChatChoice{{Message: ChatMessage{Role: "assistant"}}}}
+	var content strings.Builder
+	received := false
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			result.Choices[0].Message.Content = content.String()
+			return result, received, nil
+		}
+
+		var event chatChunk
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return nil, received, fmt.Errorf("error parsing stream event: %v", err)
+		}
+		result.ID, result.Model = event.ID, event.Model
+		if event.Usage != nil {
+			result.Usage = *event.Usage
+		}
+		for _, choice := range event.Choices {
+			if choice.Index != 0 {
+				continue
+			}
+			if choice.Delta.Content != "" {
+				content.WriteString(choice.Delta.Content)
+				received = true
+				if onDelta != nil {
+					onDelta(choice.Delta.Content)
+				}
+			}
+			if choice.FinishReason != nil {
+				result.Choices[0].FinishReason = *choice.FinishReason
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, received, fmt.Errorf("error reading stream: %v", err)
+	}
+	return nil, received, fmt.Errorf("stream ended before completion")
+}
@@ -1,15 +1,22 @@
 package mistral
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"path"
+	"path/filepath"
 	"time"
 
 	"github.com/go-resty/resty/v2"
 )
 
 const (
+	// BaseURL is the default Mistral API endpoint
 	BaseURL = "https://api.mistral.ai/v1"
 	// Maximum file size allowed by Mistral API (52.4 MB)
 	MaxFileSize = 52 * 1024 * 1024
@@ -17,12 +24,16 @@ const (
 
 // Client represents a Mistral API client
 type Client struct {
-	APIKey string
-	client *resty.Client
+	APIKey      string
+	model       string
+	client      *resty.Client
+	retryPolicy RetryPolicy
+	limits      limits
 }
 
-// NewClient creates a new Mistral API client
-func NewClient(apiKey string) *Client {
+// NewClient creates a new Mistral API client. It returns nil if no API key is
+// given and MISTRAL_API_KEY is not set.
+func NewClient(apiKey string, opts ...Option) *Client {
 	if apiKey == "" {
 		apiKey = os.Getenv("MISTRAL_API_KEY")
 		if apiKey == "" {
@@ -30,98 +41,246 @@ func NewClient(apiKey string) *Client {
 		}
 	}
 
-	return &Client{
-		APIKey: apiKey,
-		client: resty.New().
-			SetBaseURL(BaseURL).
-			SetTimeout(120 * time.Second), // Add a 2-minute timeout for OCR operations
+	cfg := clientConfig{
+		baseURL: BaseURL,
+		model:   DefaultModel,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
 	}
-}
-
-// GetFileURL returns the signed URL for an uploaded file
-func (c *Client) GetFileURL(fileID string) (string, error) {
-	// Request a signed URL with 24 hour expiry
-	resp, err := c.client.R().
-		SetHeader("Authorization", "Bearer "+c.APIKey).
-		SetHeader("Accept", "application/json").
-		Get(fmt.Sprintf("/files/%s/url?expiry=24", fileID))
 
-	if err != nil {
-		return "", fmt.Errorf("error fetching file URL: %v", err)
+	var rc *resty.Client
+	if cfg.httpClient != nil {
+		// The options below change the client and its transport, which the
+		// caller may share with other code, so they are changed on copies
+		hc := *cfg.httpClient
+		if t, ok := hc.Transport.(*http.Transport); ok && cfg.proxy != "" {
+			hc.Transport = t.Clone()
+		}
+		rc = resty.NewWithClient(&hc)
+	} else {
+		rc = resty.New().SetTimeout(DefaultTimeout)
 	}
+	rc.SetBaseURL(cfg.baseURL)
 
-	if resp.StatusCode() != 200 {
-		return "", fmt.Errorf("API returned error status: %d - %s", resp.StatusCode(), resp.String())
+	if cfg.timeout > 0 {
+		rc.SetTimeout(cfg.timeout)
+	}
+	if cfg.transport != nil {
+		rc.SetTransport(cfg.transport)
+	}
+	if cfg.proxy != "" {
+		rc.SetProxy(cfg.proxy)
+	}
+	// The recorder wraps the final transport, including the proxy
+	if cfg.recorder != nil {
+		cfg.recorder.next = rc.GetClient().Transport
+		rc.SetTransport(cfg.recorder)
+	}
+	for key, value := range cfg.headers {
+		rc.SetHeader(key, value)
+	}
+	if cfg.userAgent != "" {
+		rc.SetHeader("User-Agent", cfg.userAgent)
 	}
 
-	// Parse the response to get the signed URL
-	var urlResponse struct {
-		URL string `json:"url"`
+	retryPolicy := DefaultRetryPolicy()
+	if cfg.retry != nil {
+		retryPolicy = *cfg.retry
 	}
 
-	if err := json.Unmarshal(resp.Body(), &urlResponse); err != nil {
-		return "", fmt.Errorf("error parsing URL response: %v", err)
+	return &Client{
+		APIKey:      apiKey,
+		model:       cfg.model,
+		client:      rc,
+		retryPolicy: retryPolicy,
+		limits:      newLimits(cfg),
 	}
+}
+
+// Model returns the OCR model used by requests that do not name one
+func (c *Client) Model() string {
+	return c.model
+}
+
+// sleep pauses for d, returning early with the context error if ctx is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
 
-	if urlResponse.URL == "" {
-		return "", fmt.Errorf("API response did not contain a URL")
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
 	}
+}
+
+// GetFileURL returns the signed URL for an uploaded file
+func (c *Client) GetFileURL(fileID string) (string, error) {
+	return c.GetFileURLContext(context.Background(), fileID)
+}
+
+// GetFileURLContext is like GetFileURL but aborts the request when ctx is done
+func (c *Client) GetFileURLContext(ctx context.Context, fileID string) (string, error) {
+	var fileURL string
+	err := c.retry(ctx, func() error {
+		release, err := c.limits.other.acquire(ctx)
+		if err != nil {
+			return err
+		}
+		defer release()
 
-	return urlResponse.URL, nil
+		// Request a signed URL with 24 hour expiry
+		resp, err := c.client.R().
+			SetContext(ctx).
+			SetHeader("Authorization", "Bearer "+c.APIKey).
+			SetHeader("Accept", "application/json").
+			Get(fmt.Sprintf("/files/%s/url?expiry=24", fileID))
+
+		if err != nil {
+			return sendError(err, "error fetching file URL: %w")
+		}
+		if resp.StatusCode() != 200 {
+			return c.statusError(resp)
+		}
+
+		// Parse the response to get the signed URL
+		var urlResponse struct {
+			URL string `json:"url"`
+		}
+
+		if err := json.Unmarshal(resp.Body(), &urlResponse); err != nil {
+			return &retryError{err: fmt.Errorf("error parsing URL response: %v", err)}
+		}
+
+		if urlResponse.URL == "" {
+			return fmt.Errorf("API response did not contain a URL")
+		}
+
+		fileURL = urlResponse.URL
+		return nil
+	})
+	return fileURL, err
 }
 
 // UploadFile uploads a file to Mistral API for OCR processing
 func (c *Client) UploadFile(filePath string) (string, error) {
+	return c.UploadFileContext(context.Background(), filePath)
+}
+
+// UploadFileContext is like UploadFile but stops retrying and aborts the
+// in-flight upload when ctx is done
+func (c *Client) UploadFileContext(ctx context.Context, filePath string) (string, error) {
 	// Check file size before uploading
 	fileInfo, err := os.Stat(filePath)
 	if err != nil {
 		return "", fmt.Errorf("error checking file size: %v", err)
 	}
+	if err := checkFileSize(fileInfo.Size()); err != nil {
+		return "", err
+	}
 
-	if fileInfo.Size() > MaxFileSize {
-		return "", fmt.Errorf("file is too large (%.2f MB). Maximum allowed size is %.2f MB",
-			float64(fileInfo.Size())/1024/1024, float64(MaxFileSize)/1024/1024)
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("error opening file: %v", err)
 	}
+	defer f.Close()
+
+	return c.UploadReaderContext(ctx, f, filepath.Base(filePath), fileInfo.Size())
+}
 
-	// Add retry logic
-	maxRetries := 3
-	retryDelay := 3 * time.Second
+// UploadReader uploads the document read from r to Mistral API for OCR
+// processing. name is the file name reported to the API; without extension,
+// one matching the detected content type is added. size is the document size
+// if known, or -1.
+func (c *Client) UploadReader(r io.Reader, name string, size int64) (string, error) {
+	return c.UploadReaderContext(context.Background(), r, name, size)
+}
 
-	var lastErr error
+// UploadReaderContext is like UploadReader but stops retrying and aborts the
+// in-flight upload when ctx is done. Readers that are not an io.ReadSeeker
+// are read into memory first, so failed uploads can be sent again.
+func (c *Client) UploadReaderContext(ctx context.Context, r io.Reader, name string, size int64) (string, error) {
+	if err := checkFileSize(size); err != nil {
+		return "", err
+	}
+
+	body, ok := r.(io.ReadSeeker)
+	var start int64
+	if ok {
+		var err error
+		if start, err = body.Seek(0, io.SeekCurrent); err != nil {
+			// e.g. a pipe behind an *os.File
+			ok = false
+		} else if size < 0 {
+			end, err := body.Seek(0, io.SeekEnd)
+			if err != nil {
+				return "", fmt.Errorf("error reading document: %v", err)
+			}
+			size = end - start
+		}
+	}
+	if !ok {
+		data, err := io.ReadAll(io.LimitReader(r, MaxFileSize+1))
+		if err != nil {
+			return "", fmt.Errorf("error reading document: %v", err)
+		}
+		body, start, size = bytes.NewReader(data), 0, int64(len(data))
+	}
+	if err := checkFileSize(size); err != nil {
+		return "", err
+	}
+
+	// Detect the content type from the first bytes
+	head := make([]byte, SniffLen)
+	if _, err := body.Seek(start, io.SeekStart); err != nil {
+		return "", fmt.Errorf("error reading document: %v", err)
+	}
+	n, err := io.ReadFull(body, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("error reading document: %v", err)
+	}
+	contentType := DetectContentType(head[:n])
+	if name == "" {
+		name = "document"
+	}
+	if path.Ext(name) == "" {
+		name += ContentTypeExtension(contentType)
+	}
+
+	var fileID string
+	err = c.retry(ctx, func() error {
+		release, err := c.limits.upload.acquire(ctx)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		// Every attempt sends the document from the start
+		if _, err := body.Seek(start, io.SeekStart); err != nil {
+			return fmt.Errorf("error reading document: %v", err)
+		}
 
-	for attempt := 1; attempt <= maxRetries; attempt++ {
 		resp, err := c.client.R().
+			SetContext(ctx).
 			SetHeader("Authorization", "Bearer "+c.APIKey).
-			SetFile("file", filePath).
+			SetMultipartField("file", name, contentType, body).
 			SetFormData(map[string]string{
 				"purpose": "ocr",
 			}).
 			Post("/files")
 
 		if err != nil {
-			lastErr = fmt.Errorf("error making upload request: %v", err)
-			time.Sleep(retryDelay)
-			continue
+			return sendError(err, "error making upload request: %w")
 		}
-
 		if resp.StatusCode() != 200 {
-			errMsg := resp.String()
-			lastErr = fmt.Errorf("API returned error status: %d - %s", resp.StatusCode(), errMsg)
-
-			// Check if we should retry based on status code
-			if resp.StatusCode() >= 500 || resp.StatusCode() == 429 {
-				time.Sleep(retryDelay)
-				continue
-			}
-
-			return "", lastErr
+			return c.statusError(resp)
 		}
 
 		// Check for empty response
 		if len(resp.Body()) == 0 {
-			lastErr = fmt.Errorf("received empty response from API")
-			time.Sleep(retryDelay)
-			continue
+			return &retryError{err: fmt.Errorf("received empty response from API")}
 		}
 
 		// Parse the response to get the file ID
@@ -130,109 +289,115 @@ func (c *Client) UploadFile(filePath string) (string, error) {
 		}
 
 		if err := json.Unmarshal(resp.Body(), &fileResponse); err != nil {
-			lastErr = fmt.Errorf("error parsing response: %v", err)
-			time.Sleep(retryDelay)
-			continue
+			return &retryError{err: fmt.Errorf("error parsing response: %v", err)}
 		}
 
 		if fileResponse.ID == "" {
-			lastErr = fmt.Errorf("received response without file ID")
-			time.Sleep(retryDelay)
-			continue
+			return &retryError{err: fmt.Errorf("received response without file ID")}
 		}
 
-		// Success
-		return fileResponse.ID, nil
+		fileID = fileResponse.ID
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file: %w", err)
 	}
+	return fileID, nil
+}
 
-	return "", fmt.Errorf("failed to upload file after %d attempts: %v", maxRetries, lastErr)
+// checkFileSize rejects documents above MaxFileSize. Negative sizes are unknown.
+func checkFileSize(size int64) error {
+	if size > MaxFileSize {
+		return fmt.Errorf("file is too large (%.2f MB). Maximum allowed size is %.2f MB",
+			float64(size)/1024/1024, float64(MaxFileSize)/1024/1024)
+	}
+	return nil
 }
 
 // ProcessOCR processes a document with OCR
 func (c *Client) ProcessOCR(docType, docSource string, includeImageBase64 bool) ([]byte, error) {
-	// Create document map based on the document type
-	documentMap := map[string]interface{}{
-		"type": docType,
-	}
+	return c.ProcessOCRContext(context.Background(), docType, docSource, includeImageBase64)
+}
 
-	// Add the appropriate field based on document type
+// ProcessOCRContext is like ProcessOCR but stops retrying and aborts the
+// in-flight request when ctx is done
+func (c *Client) ProcessOCRContext(ctx context.Context, docType, docSource string, includeImageBase64 bool) ([]byte, error) {
 	switch docType {
-	case "document_url":
-		documentMap["document_url"] = docSource
-	case "image_url":
-		documentMap["image_url"] = docSource
+	case DocumentURL, ImageURL:
 	default:
 		return nil, fmt.Errorf("unsupported document type: %s", docType)
 	}
 
-	requestBody := map[string]interface{}{
-		"model":                "mistral-ocr-latest",
-		"document":             documentMap,
-		"include_image_base64": includeImageBase64,
+	return c.OCRRaw(ctx, &OCRRequest{
+		Document:           NewDocument(docType, docSource),
+		IncludeImageBase64: includeImageBase64,
+	})
+}
+
+// OCR runs OCR for req and returns the decoded result
+func (c *Client) OCR(ctx context.Context, req *OCRRequest) (*OCRResult, error) {
+	data, err := c.OCRRaw(ctx, req)
+	if err != nil {
+		return nil, err
 	}
 
-	// Add retry logic for empty responses
-	maxRetries := 5
-	retryDelay := 10 * time.Second
+	return ParseOCRResult(data)
+}
 
-	var lastErr error
-	var resp *resty.Response
+// OCRRaw runs OCR for req and returns the raw JSON response body
+func (c *Client) OCRRaw(ctx context.Context, req *OCRRequest) ([]byte, error) {
+	requestBody := *req
+	if requestBody.Model == "" {
+		requestBody.Model = c.model
+	}
 
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		resp, lastErr = c.client.R().
-			SetHeader("Content-Type", "application/json").
-			SetHeader("Authorization", "Bearer "+c.APIKey).
-			SetHeader("Accept", "application/json").
-			SetBody(requestBody).
-			Post("/ocr")
+	return c.postJSON(ctx, "/ocr", requestBody)
+}
 
-		// Check for API error status codes
-		if lastErr != nil {
-			time.Sleep(retryDelay)
-			continue
-		}
+// postJSON sends body to path and returns the raw JSON response body,
+// retrying as the retry policy allows, including empty or invalid bodies
+func (c *Client) postJSON(ctx context.Context, path string, body interface{}) ([]byte, error) {
+	return c.doJSON(ctx, http.MethodPost, path, body)
+}
 
-		// Check for non-200 status codes
-		if resp.StatusCode() != 200 {
-			var errMsg string
-			if len(resp.Body()) > 0 {
-				errMsg = string(resp.Body())
-			} else {
-				errMsg = resp.Status()
-			}
+// doJSON is like postJSON for any method. body is not sent if nil.
+func (c *Client) doJSON(ctx context.Context, method, path string, body interface{}) ([]byte, error) {
+	var data []byte
+	err := c.retry(ctx, func() error {
+		release, err := c.limits.forRequest(method, path).acquire(ctx)
+		if err != nil {
+			return err
+		}
+		defer release()
 
-			// Check for specific error codes that might indicate we should retry
-			if resp.StatusCode() >= 500 || resp.StatusCode() == 429 {
-				lastErr = fmt.Errorf("API returned error status: %d - %s", resp.StatusCode(), errMsg)
-				time.Sleep(retryDelay)
-				continue
-			}
+		r := c.client.R().
+			SetContext(ctx).
+			SetHeader("Authorization", "Bearer "+c.APIKey).
+			SetHeader("Accept", "application/json")
+		if body != nil {
+			r.SetHeader("Content-Type", "application/json").SetBody(body)
+		}
+		resp, err := r.Execute(method, path)
 
-			// For other errors, don't retry
-			return nil, fmt.Errorf("API returned error status: %d - %s", resp.StatusCode(), errMsg)
+		if err != nil {
+			return sendError(err, "error making request: %w")
+		}
+		if resp.StatusCode() != 200 {
+			return c.statusError(resp)
 		}
 
 		// Check for empty response
 		if len(resp.Body()) == 0 {
-			lastErr = fmt.Errorf("received empty response from API")
-
-			// For empty responses, try with a longer delay
-			adjustedDelay := retryDelay * time.Duration(attempt)
-			time.Sleep(adjustedDelay)
-			continue
+			return &retryError{err: fmt.Errorf("received empty response from API")}
 		}
 
 		// Check if response appears to be valid JSON
 		if !json.Valid(resp.Body()) {
-			lastErr = fmt.Errorf("received invalid JSON response from API")
-			time.Sleep(retryDelay)
-			continue
+			return &retryError{err: fmt.Errorf("received invalid JSON response from API")}
 		}
 
-		// If we got here, we have a valid response
-		return resp.Body(), nil
-	}
-
-	// If we've exhausted all retries, provide a detailed error
-	return nil, fmt.Errorf("failed after %d attempts. Last error: %v", maxRetries, lastErr)
+		data = resp.Body()
+		return nil
+	})
+	return data, err
 }
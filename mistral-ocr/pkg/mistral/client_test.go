@@ -0,0 +1,190 @@
+package mistral_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral/mistraltest"
+)
+
+// newTestClient starts srv and returns a client for it that retries
+// quickly. The server is closed when the test ends.
+func newTestClient(t *testing.T, srv *mistraltest.Server, policy mistral.RetryPolicy) *mistral.Client {
+	t.Helper()
+	ts := srv.Start()
+	t.Cleanup(ts.Close)
+	return mistral.NewClient("test", mistral.WithBaseURL(ts.URL), mistral.WithRetryPolicy(policy))
+}
+
+func fastRetries(attempts int) mistral.RetryPolicy {
+	return mistral.RetryPolicy{MaxAttempts: attempts, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+}
+
+var ocrRequest = &mistral.OCRRequest{Document: mistral.NewDocument(mistral.DocumentURL, "https://example.com/doc.pdf")}
+
+func TestOCRRetriesRateLimitWithRetryAfter(t *testing.T) {
+	srv := mistraltest.NewServer()
+	srv.Fail(mistraltest.Failure{Endpoint: mistraltest.EndpointOCR, Status: http.StatusTooManyRequests, Times: 1, RetryAfter: "1"})
+
+	// Retry-After replaces the much shorter backoff, capped by MaxDelay
+	policy := mistral.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 200 * time.Millisecond}
+	client := newTestClient(t, srv, policy)
+
+	start := time.Now()
+	result, err := client.OCR(context.Background(), ocrRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < policy.MaxDelay {
+		t.Errorf("retried after %v, want the Retry-After delay capped at %v", elapsed, policy.MaxDelay)
+	}
+	if len(result.Pages) != 1 {
+		t.Errorf("got %d pages, want 1", len(result.Pages))
+	}
+	if n := srv.Count(mistraltest.EndpointOCR); n != 2 {
+		t.Errorf("sent %d OCR requests, want 2", n)
+	}
+}
+
+func TestOCRServerErrorsExhaustRetries(t *testing.T) {
+	srv := mistraltest.NewServer()
+	srv.Fail(mistraltest.Failure{Endpoint: mistraltest.EndpointOCR, Status: http.StatusServiceUnavailable})
+	client := newTestClient(t, srv, fastRetries(3))
+
+	_, err := client.OCR(context.Background(), ocrRequest)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.Contains(err.Error(), "failed after 3 attempts") {
+		t.Errorf("error %q does not report the attempts", err)
+	}
+
+	var apiErr *mistral.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("error %v does not wrap an *APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusServiceUnavailable || apiErr.Category() != mistral.CategoryServer || !apiErr.Retryable {
+		t.Errorf("unexpected API error %+v", apiErr)
+	}
+	if apiErr.RequestID == "" {
+		t.Error("API error has no request ID")
+	}
+	if n := srv.Count(mistraltest.EndpointOCR); n != 3 {
+		t.Errorf("sent %d OCR requests, want 3", n)
+	}
+}
+
+func TestOCRDoesNotRetryInvalidRequests(t *testing.T) {
+	srv := mistraltest.NewServer()
+	srv.Fail(mistraltest.Failure{Endpoint: mistraltest.EndpointOCR, Status: http.StatusUnprocessableEntity})
+	client := newTestClient(t, srv, fastRetries(3))
+
+	_, err := client.OCR(context.Background(), ocrRequest)
+	var apiErr *mistral.APIError
+	if !errors.As(err, &apiErr) || apiErr.Category() != mistral.CategoryInvalidRequest {
+		t.Fatalf("expected an invalid request error, got %v", err)
+	}
+	if n := srv.Count(mistraltest.EndpointOCR); n != 1 {
+		t.Errorf("sent %d OCR requests, want 1", n)
+	}
+}
+
+func TestOCREmptyBody(t *testing.T) {
+	tests := []struct {
+		name  string
+		times int
+	}{
+		{name: "retried", times: 1},
+		{name: "every attempt", times: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := mistraltest.NewServer()
+			srv.Fail(mistraltest.Failure{Endpoint: mistraltest.EndpointOCR, Empty: true, Times: tt.times})
+			client := newTestClient(t, srv, fastRetries(2))
+
+			_, err := client.OCR(context.Background(), ocrRequest)
+			if tt.times > 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), "empty response") {
+				t.Fatalf("expected an empty response error, got %v", err)
+			}
+			if n := srv.Count(mistraltest.EndpointOCR); n != 2 {
+				t.Errorf("sent %d OCR requests, want 2", n)
+			}
+		})
+	}
+}
+
+// onlyReader hides the Seek method of a reader
+type onlyReader struct {
+	io.Reader
+}
+
+func TestUploadRetrySendsWholeDocument(t *testing.T) {
+	doc := append([]byte("%PDF-1.4\n"), bytes.Repeat([]byte("0123456789"), 10000)...)
+
+	tests := []struct {
+		name   string
+		reader func() io.Reader
+		size   int64
+	}{
+		{name: "seekable", reader: func() io.Reader { return bytes.NewReader(doc) }, size: int64(len(doc))},
+		{name: "not seekable", reader: func() io.Reader { return onlyReader{bytes.NewReader(doc)} }, size: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := mistraltest.NewServer()
+			srv.Fail(mistraltest.Failure{Endpoint: mistraltest.EndpointUpload, Status: http.StatusBadGateway, Times: 2})
+			client := newTestClient(t, srv, fastRetries(3))
+
+			id, err := client.UploadReaderContext(context.Background(), tt.reader(), "doc", tt.size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := srv.Count(mistraltest.EndpointUpload); n != 3 {
+				t.Errorf("sent %d uploads, want 3", n)
+			}
+
+			file := srv.File(id)
+			if file == nil {
+				t.Fatalf("file %s was not stored", id)
+			}
+			if !bytes.Equal(file.Data, doc) {
+				t.Errorf("server received %d bytes, want the whole %d byte document", len(file.Data), len(doc))
+			}
+			if file.Name != "doc.pdf" {
+				t.Errorf("file name = %q, want the detected extension added", file.Name)
+			}
+		})
+	}
+}
+
+func TestWithHTTPClientLeavesCallerClientAlone(t *testing.T) {
+	transport := &http.Transport{}
+	hc := &http.Client{Transport: transport, Timeout: time.Minute}
+
+	mistral.NewClient("test", mistral.WithHTTPClient(hc), mistral.WithProxy("http://127.0.0.1:3128"), mistral.WithTimeout(time.Second))
+	if hc.Transport != transport || hc.Timeout != time.Minute {
+		t.Errorf("caller's client was changed: %+v", hc)
+	}
+	if transport.Proxy != nil {
+		t.Error("caller's transport got the proxy")
+	}
+
+	mistral.NewClient("test", mistral.WithHTTPClient(hc), mistral.WithTransport(http.DefaultTransport))
+	if hc.Transport != transport {
+		t.Error("caller's transport was replaced")
+	}
+}
@@ -0,0 +1,78 @@
+package mistral
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// Content types of documents and images accepted for OCR
+const (
+	ContentTypePDF  = "application/pdf"
+	ContentTypeDOCX = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	ContentTypePPTX = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+	ContentTypePNG  = "image/png"
+	ContentTypeJPEG = "image/jpeg"
+	ContentTypeGIF  = "image/gif"
+	ContentTypeWebP = "image/webp"
+	ContentTypeTIFF = "image/tiff"
+	ContentTypeBMP  = "image/bmp"
+	ContentTypeAVIF = "image/avif"
+)
+
+// contentExtensions maps detected content types to file extensions
+var contentExtensions = map[string]string{
+	ContentTypePDF:  ".pdf",
+	ContentTypeDOCX: ".docx",
+	ContentTypePPTX: ".pptx",
+	ContentTypePNG:  ".png",
+	ContentTypeJPEG: ".jpg",
+	ContentTypeGIF:  ".gif",
+	ContentTypeWebP: ".webp",
+	ContentTypeTIFF: ".tiff",
+	ContentTypeBMP:  ".bmp",
+	ContentTypeAVIF: ".avif",
+}
+
+// SniffLen is the number of leading bytes DetectContentType needs to tell all
+// supported document types apart
+const SniffLen = 4096
+
+// DetectContentType returns the content type of a document from its magic
+// bytes, given the first SniffLen bytes or less. Unknown content falls back
+// to http.DetectContentType.
+func DetectContentType(head []byte) string {
+	switch {
+	case bytes.HasPrefix(bytes.TrimLeft(head, "\x00\t\n\f\r "), []byte("%PDF-")):
+		return ContentTypePDF
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		return ContentTypePNG
+	case bytes.HasPrefix(head, []byte("\xff\xd8\xff")):
+		return ContentTypeJPEG
+	case bytes.HasPrefix(head, []byte("GIF87a")), bytes.HasPrefix(head, []byte("GIF89a")):
+		return ContentTypeGIF
+	case len(head) >= 12 && bytes.Equal(head[:4], []byte("RIFF")) && bytes.Equal(head[8:12], []byte("WEBP")):
+		return ContentTypeWebP
+	case bytes.HasPrefix(head, []byte("II*\x00")), bytes.HasPrefix(head, []byte("MM\x00*")):
+		return ContentTypeTIFF
+	case bytes.HasPrefix(head, []byte("BM")) && len(head) >= 14:
+		return ContentTypeBMP
+	case len(head) >= 12 && bytes.Equal(head[4:12], []byte("ftypavif")):
+		return ContentTypeAVIF
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		// Office documents are ZIP archives; the names of their first
+		// entries tell them apart
+		switch {
+		case bytes.Contains(head, []byte("word/")):
+			return ContentTypeDOCX
+		case bytes.Contains(head, []byte("ppt/")):
+			return ContentTypePPTX
+		}
+	}
+	return http.DetectContentType(head)
+}
+
+// ContentTypeExtension returns the file extension of a content type returned
+// by DetectContentType, or "" if it is not a known document type
+func ContentTypeExtension(contentType string) string {
+	return contentExtensions[contentType]
+}
@@ -0,0 +1,102 @@
+package mistral
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// DefaultEmbeddingModel is the embedding model used when none is given
+	DefaultEmbeddingModel = "mistral-embed"
+	// DefaultEmbeddingBatchSize is the number of inputs sent per embeddings request
+	DefaultEmbeddingBatchSize = 32
+)
+
+// EmbeddingRequest is the body of an embeddings request
+type EmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// EmbeddingResponse is the response of the embeddings endpoint
+type EmbeddingResponse struct {
+	ID     string      `json:"id"`
+	Object string      `json:"object"`
+	Model  string      `json:"model"`
+	Data   []Embedding `json:"data"`
+	Usage  Usage       `json:"usage"`
+}
+
+// Embedding is the vector of one input, identified by its position in the request
+type Embedding struct {
+	Object    string    `json:"object"`
+	Embedding []float64 `json:"embedding"`
+	Index     int       `json:"index"`
+}
+
+// Usage reports the tokens consumed by a request
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens,omitempty"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Embed returns the embeddings of req.Input. It is retried like OCR requests.
+func (c *Client) Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	requestBody := *req
+	if requestBody.Model == "" {
+		requestBody.Model = DefaultEmbeddingModel
+	}
+
+	data, err := c.postJSON(ctx, "/embeddings", requestBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp EmbeddingResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("error parsing embeddings response: %v", err)
+	}
+	if len(resp.Data) != len(requestBody.Input) {
+		return nil, fmt.Errorf("API returned %d embeddings for %d inputs", len(resp.Data), len(requestBody.Input))
+	}
+	return &resp, nil
+}
+
+// EmbedBatched embeds inputs in requests of at most batchSize inputs and
+// returns the vectors in input order together with the total token usage.
+// progress, if not nil, is called after every batch with the number of
+// inputs embedded so far.
+func (c *Client) EmbedBatched(ctx context.Context, model string, inputs []string, batchSize int, progress func(done int)) ([][]float64, Usage, error) {
+	if batchSize < 1 {
+		batchSize = DefaultEmbeddingBatchSize
+	}
+
+	vectors := make([][]float64, len(inputs))
+	var usage Usage
+	for start := 0; start < len(inputs); start += batchSize {
+		end := start + batchSize
+		if end > len(inputs) {
+			end = len(inputs)
+		}
+
+		resp, err := c.Embed(ctx, &EmbeddingRequest{Model: model, Input: inputs[start:end]})
+		if err != nil {
+			return nil, usage, fmt.Errorf("batch %d: %w", start/batchSize+1, err)
+		}
+		for _, e := range resp.Data {
+			if e.Index < 0 || e.Index >= end-start {
+				return nil, usage, fmt.Errorf("batch %d: embedding index %d out of range", start/batchSize+1, e.Index)
+			}
+			vectors[start+e.Index] = e.Embedding
+		}
+		usage.PromptTokens += resp.Usage.PromptTokens
+		usage.TotalTokens += resp.Usage.TotalTokens
+
+		if progress != nil {
+			progress(end)
+		}
+	}
+	return vectors, usage, nil
+}
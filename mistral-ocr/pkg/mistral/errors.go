@@ -0,0 +1,123 @@
+package mistral
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrorCategory groups API errors by what the caller can do about them
+type ErrorCategory string
+
+// Error categories
+const (
+	// CategoryAuth means the API key is missing, invalid or lacks permission
+	CategoryAuth ErrorCategory = "auth"
+	// CategoryQuota means a rate limit or the billing quota was hit
+	CategoryQuota ErrorCategory = "quota"
+	// CategoryInvalidRequest means the request or the document was rejected
+	CategoryInvalidRequest ErrorCategory = "invalid_request"
+	// CategoryServer means the API failed to handle a valid request
+	CategoryServer ErrorCategory = "server"
+	// CategoryOther covers all other statuses
+	CategoryOther ErrorCategory = "other"
+)
+
+// APIError is an error response of the Mistral API. Errors returned by the
+// client wrap it, so it can be retrieved with errors.As.
+type APIError struct {
+	// StatusCode is the HTTP status of the response
+	StatusCode int
+	// Type and Code are the error type and code reported by the API, if any
+	Type string
+	Code string
+	// Message is the error message, or the raw response body if it had none
+	Message string
+	// RequestID identifies the request in support requests
+	RequestID string
+	// Retryable reports whether the retry policy retries the status
+	Retryable bool
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("API returned error status: %d - %s", e.StatusCode, e.Message)
+	if e.RequestID != "" {
+		msg += " (request ID " + e.RequestID + ")"
+	}
+	return msg
+}
+
+// Category returns the category of the error's status
+func (e *APIError) Category() ErrorCategory {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return CategoryAuth
+	case e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusPaymentRequired:
+		return CategoryQuota
+	case e.StatusCode >= 500:
+		return CategoryServer
+	case e.StatusCode >= 400:
+		return CategoryInvalidRequest
+	default:
+		return CategoryOther
+	}
+}
+
+// requestIDHeaders are the response headers that may carry the request ID
+var requestIDHeaders = []string{"X-Request-Id", "Mistral-Correlation-Id", "X-Kong-Request-Id"}
+
+// newAPIError builds the error for a response with status, header and body
+func newAPIError(status int, header http.Header, body []byte) *APIError {
+	e := &APIError{StatusCode: status}
+	for _, name := range requestIDHeaders {
+		if id := header.Get(name); id != "" {
+			e.RequestID = id
+			break
+		}
+	}
+
+	var payload struct {
+		Message   json.RawMessage `json:"message"`
+		Detail    json.RawMessage `json:"detail"`
+		Type      string          `json:"type"`
+		Code      json.RawMessage `json:"code"`
+		RequestID string          `json:"request_id"`
+	}
+	if json.Unmarshal(body, &payload) == nil {
+		e.Type = payload.Type
+		e.Code = jsonText(payload.Code)
+		e.Message = jsonText(payload.Message)
+		if e.Message == "" {
+			e.Message = jsonText(payload.Detail)
+		}
+		if e.RequestID == "" {
+			e.RequestID = payload.RequestID
+		}
+	}
+
+	if e.Message == "" {
+		e.Message = strings.TrimSpace(string(body))
+	}
+	if e.Message == "" {
+		e.Message = http.StatusText(status)
+	}
+	return e
+}
+
+// jsonText returns a JSON string as text and other JSON values compacted
+func jsonText(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var buf bytes.Buffer
+	if json.Compact(&buf, raw) != nil {
+		return string(raw)
+	}
+	return buf.String()
+}
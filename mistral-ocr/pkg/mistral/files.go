@@ -0,0 +1,126 @@
+package mistral
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// File is the metadata of a file uploaded to the API
+type File struct {
+	ID         string `json:"id"`
+	Object     string `json:"object"`
+	Bytes      int64  `json:"bytes"`
+	CreatedAt  int64  `json:"created_at"`
+	Filename   string `json:"filename"`
+	Purpose    string `json:"purpose"`
+	SampleType string `json:"sample_type,omitempty"`
+	NumLines   *int   `json:"num_lines,omitempty"`
+	Source     string `json:"source,omitempty"`
+}
+
+// Created returns the upload time of the file
+func (f File) Created() time.Time {
+	return time.Unix(f.CreatedAt, 0)
+}
+
+// FileList is a page of files returned by ListFiles
+type FileList struct {
+	Object string `json:"object"`
+	Data   []File `json:"data"`
+	Total  int    `json:"total"`
+}
+
+// ListFilesOptions filters and pages the files returned by ListFiles
+type ListFilesOptions struct {
+	// Page is the zero-based page number
+	Page int
+	// PageSize is the number of files per page (API default if zero)
+	PageSize int
+	// Purpose only lists files uploaded for this purpose, e.g. "ocr"
+	Purpose string
+	// Search only lists files whose name contains this text
+	Search string
+}
+
+// ListFiles returns a page of the files uploaded with the API key
+func (c *Client) ListFiles(ctx context.Context, opts ListFilesOptions) (*FileList, error) {
+	query := url.Values{}
+	query.Set("page", strconv.Itoa(opts.Page))
+	if opts.PageSize > 0 {
+		query.Set("page_size", strconv.Itoa(opts.PageSize))
+	}
+	if opts.Purpose != "" {
+		query.Set("purpose", opts.Purpose)
+	}
+	if opts.Search != "" {
+		query.Set("search", opts.Search)
+	}
+
+	data, err := c.doJSON(ctx, http.MethodGet, "/files?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error listing files: %w", err)
+	}
+
+	var list FileList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("error parsing file list: %v", err)
+	}
+	return &list, nil
+}
+
+// ListAllFiles returns the files of all pages matching opts. opts.Page is ignored.
+func (c *Client) ListAllFiles(ctx context.Context, opts ListFilesOptions) ([]File, error) {
+	if opts.PageSize <= 0 {
+		opts.PageSize = 100
+	}
+
+	var files []File
+	for opts.Page = 0; ; opts.Page++ {
+		list, err := c.ListFiles(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, list.Data...)
+		if len(list.Data) < opts.PageSize || (list.Total > 0 && len(files) >= list.Total) {
+			return files, nil
+		}
+	}
+}
+
+// GetFile returns the metadata of the uploaded file with fileID
+func (c *Client) GetFile(ctx context.Context, fileID string) (*File, error) {
+	data, err := c.doJSON(ctx, http.MethodGet, "/files/"+url.PathEscape(fileID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving file %s: %w", fileID, err)
+	}
+
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("error parsing file: %v", err)
+	}
+	return &file, nil
+}
+
+// DeleteFile deletes the uploaded file with fileID
+func (c *Client) DeleteFile(ctx context.Context, fileID string) error {
+	data, err := c.doJSON(ctx, http.MethodDelete, "/files/"+url.PathEscape(fileID), nil)
+	if err != nil {
+		return fmt.Errorf("error deleting file %s: %w", fileID, err)
+	}
+
+	var resp struct {
+		Deleted bool `json:"deleted"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return fmt.Errorf("error parsing delete response: %v", err)
+	}
+	if !resp.Deleted {
+		return fmt.Errorf("API did not delete file %s", fileID)
+	}
+	return nil
+}
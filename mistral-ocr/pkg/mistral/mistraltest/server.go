@@ -0,0 +1,549 @@
+// Package mistraltest provides an in-memory emulation of the Mistral files
+// and OCR endpoints for exercising clients without network access or an API
+// key. Failures such as 429, 500 or empty bodies can be injected to verify
+// retry behaviour.
+//
+//	srv := mistraltest.NewServer()
+//	srv.Fail(mistraltest.Failure{Endpoint: mistraltest.EndpointOCR, Status: 429, Times: 2})
+//	ts := srv.Start()
+//	defer ts.Close()
+//	client := mistral.NewClient("test", mistral.WithBaseURL(ts.URL))
+package mistraltest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/pdf"
+)
+
+// Endpoints that requests are counted by and failures can be injected into
+const (
+	EndpointUpload     = "files"
+	EndpointList       = "files-list"
+	EndpointFile       = "file"
+	EndpointFileDelete = "file-delete"
+	EndpointFileURL    = "file-url"
+	EndpointContent    = "file-content"
+	EndpointOCR        = "ocr"
+)
+
+// File is a file uploaded to the server
+type File struct {
+	ID        string
+	Name      string
+	Purpose   string
+	Data      []byte
+	CreatedAt time.Time
+}
+
+// Request is a request received by the server
+type Request struct {
+	Endpoint string
+	Method   string
+	Path     string
+	// Status is the status code the server responded with
+	Status int
+}
+
+// Failure makes requests to an endpoint fail
+type Failure struct {
+	Endpoint string
+	// Status is the status code to respond with. Empty failures default to 200.
+	Status int
+	// Empty sends a response without body
+	Empty bool
+	// Times limits the failure to this many requests; 0 fails every request
+	Times int
+	// RetryAfter is sent as Retry-After header if set
+	RetryAfter string
+}
+
+// ParseFailure parses a failure written as endpoint=kind[:times], where kind
+// is an HTTP status code or "empty", e.g. "ocr=429:2" or "files=empty"
+func ParseFailure(s string) (Failure, error) {
+	endpoint, spec, ok := strings.Cut(s, "=")
+	if !ok {
+		return Failure{}, fmt.Errorf("invalid failure %q, expected endpoint=status[:times]", s)
+	}
+
+	f := Failure{Endpoint: endpoint}
+	switch endpoint {
+	case EndpointUpload, EndpointList, EndpointFile, EndpointFileDelete, EndpointFileURL, EndpointContent, EndpointOCR:
+	default:
+		return Failure{}, fmt.Errorf("unknown endpoint %q in failure %q", endpoint, s)
+	}
+
+	kind, times, hasTimes := strings.Cut(spec, ":")
+	if hasTimes {
+		n, err := strconv.Atoi(times)
+		if err != nil || n < 0 {
+			return Failure{}, fmt.Errorf("invalid count in failure %q", s)
+		}
+		f.Times = n
+	}
+
+	if kind == "empty" {
+		f.Empty = true
+		return f, nil
+	}
+	status, err := strconv.Atoi(kind)
+	if err != nil || status < 100 || status > 599 {
+		return Failure{}, fmt.Errorf("invalid status in failure %q", s)
+	}
+	f.Status = status
+	if status == http.StatusTooManyRequests {
+		f.RetryAfter = "1"
+	}
+	return f, nil
+}
+
+// OCRFunc builds the response of an OCR request. file is the uploaded file
+// the document URL refers to, or nil for other documents.
+type OCRFunc func(req *mistral.OCRRequest, file *File) (*mistral.OCRResult, error)
+
+// Canned returns an OCRFunc that responds with result to every request
+func Canned(result *mistral.OCRResult) OCRFunc {
+	return func(req *mistral.OCRRequest, file *File) (*mistral.OCRResult, error) {
+		return result, nil
+	}
+}
+
+// DefaultOCR responds with a page of placeholder Markdown for every page of
+// the document. Uploaded PDFs get their real page count, other documents one
+// page. Requested page indices are honoured.
+func DefaultOCR(req *mistral.OCRRequest, file *File) (*mistral.OCRResult, error) {
+	name, pageCount, size := "document", 1, 0
+	if file != nil {
+		name, size = file.Name, len(file.Data)
+		if doc, err := pdf.NewReader(file.Data); err == nil {
+			pageCount = doc.NumPages()
+		}
+	} else if url := req.Document.DocumentURL + req.Document.ImageURL; url != "" {
+		name = path.Base(strings.SplitN(url, "?", 2)[0])
+	}
+
+	indices := req.Pages
+	if len(indices) == 0 {
+		for i := 0; i < pageCount; i++ {
+			indices = append(indices, i)
+		}
+	}
+
+	result := &mistral.OCRResult{Model: req.Model, Pages: []mistral.Page{}}
+	for _, index := range indices {
+		if index < 0 || index >= pageCount {
+			return nil, fmt.Errorf("page %d is out of range, the document has %d pages", index, pageCount)
+		}
+		result.Pages = append(result.Pages, mistral.Page{
+			Index:      index,
+			Markdown:   fmt.Sprintf("# %s\n\nMock OCR text of page %d.", name, index+1),
+			Dimensions: mistral.Dimensions{DPI: 200, Height: 2200, Width: 1700},
+		})
+	}
+	result.UsageInfo = mistral.UsageInfo{PagesProcessed: len(result.Pages), DocSizeBytes: size}
+	if req.DocumentAnnotationFormat != nil {
+		result.DocumentAnnotation = "{}"
+	}
+	return result, nil
+}
+
+// Server emulates the /files, /files/{id}, /files/{id}/url and /ocr
+// endpoints. Paths are accepted with and without the /v1 prefix.
+type Server struct {
+	// APIKey is the only bearer token accepted if set; otherwise any is
+	APIKey string
+	// OCR builds OCR responses (DefaultOCR if nil)
+	OCR OCRFunc
+	// Latency delays every response
+	Latency time.Duration
+
+	mu       sync.Mutex
+	files    map[string]*File
+	failures []*Failure
+	requests []Request
+	nextID   int
+}
+
+// NewServer returns a server without files or failures
+func NewServer() *Server {
+	return &Server{files: make(map[string]*File)}
+}
+
+// Start serves s on a local port. The URL of the returned server is the base
+// URL for mistral.WithBaseURL; close it when done.
+func (s *Server) Start() *httptest.Server {
+	return httptest.NewServer(s)
+}
+
+// Fail injects a failure. Failures are matched in the order they were added.
+func (s *Server) Fail(f Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, &f)
+}
+
+// Requests returns the requests received so far
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Count returns the number of requests received by endpoint
+func (s *Server) Count(endpoint string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, r := range s.requests {
+		if r.Endpoint == endpoint {
+			n++
+		}
+	}
+	return n
+}
+
+// File returns the uploaded file with id, or nil
+func (s *Server) File(id string) *File {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.files[id]
+}
+
+var (
+	filePath        = regexp.MustCompile(`^/files/([^/]+)$`)
+	fileURLPath     = regexp.MustCompile(`^/files/([^/]+)/url$`)
+	fileContentPath = regexp.MustCompile(`^/files/([^/]+)/content$`)
+)
+
+// statusWriter remembers the status code of a response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p := strings.TrimPrefix(r.URL.Path, "/v1")
+	prefix := strings.TrimSuffix(r.URL.Path, p)
+
+	var endpoint string
+	var handle func(http.ResponseWriter, *http.Request)
+	switch {
+	case p == "/files" && r.Method == http.MethodPost:
+		endpoint, handle = EndpointUpload, s.handleUpload
+	case p == "/files" && r.Method == http.MethodGet:
+		endpoint, handle = EndpointList, s.handleList
+	case filePath.MatchString(p) && r.Method == http.MethodGet:
+		id := filePath.FindStringSubmatch(p)[1]
+		endpoint = EndpointFile
+		handle = func(w http.ResponseWriter, r *http.Request) { s.handleFile(w, r, id) }
+	case filePath.MatchString(p) && r.Method == http.MethodDelete:
+		id := filePath.FindStringSubmatch(p)[1]
+		endpoint = EndpointFileDelete
+		handle = func(w http.ResponseWriter, r *http.Request) { s.handleDelete(w, r, id) }
+	case fileURLPath.MatchString(p) && r.Method == http.MethodGet:
+		id := fileURLPath.FindStringSubmatch(p)[1]
+		endpoint = EndpointFileURL
+		handle = func(w http.ResponseWriter, r *http.Request) { s.handleFileURL(w, r, id, prefix) }
+	case fileContentPath.MatchString(p) && r.Method == http.MethodGet:
+		id := fileContentPath.FindStringSubmatch(p)[1]
+		endpoint = EndpointContent
+		handle = func(w http.ResponseWriter, r *http.Request) { s.handleContent(w, r, id) }
+	case p == "/ocr" && r.Method == http.MethodPost:
+		endpoint, handle = EndpointOCR, s.handleOCR
+	default:
+		endpoint = "unknown"
+		handle = func(w http.ResponseWriter, r *http.Request) {
+			writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("no route for %s %s", r.Method, r.URL.Path))
+		}
+	}
+
+	sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	s.mu.Lock()
+	sw.Header().Set("X-Request-Id", fmt.Sprintf("mock-%06d", len(s.requests)+1))
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.requests = append(s.requests, Request{Endpoint: endpoint, Method: r.Method, Path: r.URL.Path, Status: sw.status})
+		s.mu.Unlock()
+	}()
+
+	if s.Latency > 0 {
+		select {
+		case <-time.After(s.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	// Signed URLs are fetched without the API key
+	if endpoint != EndpointContent && !s.authorized(r) {
+		writeError(sw, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+	if s.fail(sw, r, endpoint) {
+		return
+	}
+	handle(sw, r)
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" || token == r.Header.Get("Authorization") {
+		return false
+	}
+	return s.APIKey == "" || token == s.APIKey
+}
+
+// fail writes the response of the first injected failure matching endpoint
+func (s *Server) fail(w http.ResponseWriter, r *http.Request, endpoint string) bool {
+	s.mu.Lock()
+	var failure *Failure
+	for i, f := range s.failures {
+		if f.Endpoint != endpoint {
+			continue
+		}
+		failure = f
+		if f.Times > 0 {
+			if f.Times--; f.Times == 0 {
+				s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			}
+		}
+		break
+	}
+	s.mu.Unlock()
+
+	if failure == nil {
+		return false
+	}
+	// Drain the body so the client is not cut off mid-upload
+	io.Copy(io.Discard, r.Body)
+
+	if failure.RetryAfter != "" {
+		w.Header().Set("Retry-After", failure.RetryAfter)
+	}
+	status := failure.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if failure.Empty {
+		w.WriteHeader(status)
+		return true
+	}
+	writeError(w, status, "injected_failure", fmt.Sprintf("injected failure: %d %s", status, http.StatusText(status)))
+	return true
+}
+
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(64 << 20); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("invalid multipart body: %v", err))
+		return
+	}
+	upload, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "field 'file' is required")
+		return
+	}
+	defer upload.Close()
+	data, err := io.ReadAll(upload)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	s.nextID++
+	f := &File{
+		ID:        fmt.Sprintf("file-%08d", s.nextID),
+		Name:      header.Filename,
+		Purpose:   r.FormValue("purpose"),
+		Data:      data,
+		CreatedAt: time.Now(),
+	}
+	s.files[f.ID] = f
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, f.metadata())
+}
+
+// metadata returns f as the API describes files
+func (f *File) metadata() mistral.File {
+	return mistral.File{
+		ID:        f.ID,
+		Object:    "file",
+		Bytes:     int64(len(f.Data)),
+		CreatedAt: f.CreatedAt.Unix(),
+		Filename:  f.Name,
+		Purpose:   f.Purpose,
+		Source:    "upload",
+	}
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	pageSize, err := strconv.Atoi(query.Get("page_size"))
+	if err != nil || pageSize <= 0 {
+		pageSize = 100
+	}
+
+	s.mu.Lock()
+	var files []mistral.File
+	for _, f := range s.files {
+		if purpose := query.Get("purpose"); purpose != "" && f.Purpose != purpose {
+			continue
+		}
+		if !strings.Contains(f.Name, query.Get("search")) {
+			continue
+		}
+		files = append(files, f.metadata())
+	}
+	s.mu.Unlock()
+
+	// Newest first, like the API
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].CreatedAt != files[j].CreatedAt {
+			return files[i].CreatedAt > files[j].CreatedAt
+		}
+		return files[i].ID > files[j].ID
+	})
+
+	list := mistral.FileList{Object: "list", Data: []mistral.File{}, Total: len(files)}
+	if start := page * pageSize; page >= 0 && start < len(files) {
+		end := start + pageSize
+		if end > len(files) {
+			end = len(files)
+		}
+		list.Data = files[start:end]
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) handleFile(w http.ResponseWriter, r *http.Request, id string) {
+	f := s.File(id)
+	if f == nil {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("file %s not found", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, f.metadata())
+}
+
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request, id string) {
+	s.mu.Lock()
+	_, ok := s.files[id]
+	delete(s.files, id)
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("file %s not found", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"id": id, "object": "file", "deleted": true})
+}
+
+func (s *Server) handleFileURL(w http.ResponseWriter, r *http.Request, id, prefix string) {
+	if s.File(id) == nil {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("file %s not found", id))
+		return
+	}
+	url := fmt.Sprintf("http://%s%s/files/%s/content?signature=mock", r.Host, prefix, id)
+	writeJSON(w, http.StatusOK, map[string]string{"url": url})
+}
+
+func (s *Server) handleContent(w http.ResponseWriter, r *http.Request, id string) {
+	f := s.File(id)
+	if f == nil {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("file %s not found", id))
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(f.Data)
+}
+
+func (s *Server) handleOCR(w http.ResponseWriter, r *http.Request) {
+	var req mistral.OCRRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("invalid JSON body: %v", err))
+		return
+	}
+	if req.Model == "" {
+		writeError(w, http.StatusUnprocessableEntity, "invalid_request_error", "model is required")
+		return
+	}
+
+	var url string
+	switch req.Document.Type {
+	case mistral.DocumentURL:
+		url = req.Document.DocumentURL
+	case mistral.ImageURL:
+		url = req.Document.ImageURL
+	default:
+		writeError(w, http.StatusUnprocessableEntity, "invalid_request_error", fmt.Sprintf("unsupported document type %q", req.Document.Type))
+		return
+	}
+	if url == "" {
+		writeError(w, http.StatusUnprocessableEntity, "invalid_request_error", "document URL is required")
+		return
+	}
+
+	// Signed URLs of this server refer to uploaded files
+	var file *File
+	if u, err := neturl.Parse(url); err == nil && u.Host == r.Host {
+		if m := fileContentPath.FindStringSubmatch(strings.TrimPrefix(u.Path, "/v1")); m != nil {
+			if file = s.File(m[1]); file == nil {
+				writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("file %s not found", m[1]))
+				return
+			}
+		}
+	}
+
+	ocr := s.OCR
+	if ocr == nil {
+		ocr = DefaultOCR
+	}
+	result, err := ocr(&req, file)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "invalid_request_error", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// writeError responds with an error body shaped like those of the Mistral API
+func writeError(w http.ResponseWriter, status int, errType, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"object":  "error",
+		"message": message,
+		"type":    errType,
+		"param":   nil,
+		"code":    strconv.Itoa(status),
+	})
+}
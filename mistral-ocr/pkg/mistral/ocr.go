@@ -0,0 +1,198 @@
+package mistral
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// DefaultModel is the OCR model used when a request does not name one
+const DefaultModel = "mistral-ocr-latest"
+
+// Document types accepted by the OCR endpoint
+const (
+	DocumentURL = "document_url"
+	ImageURL    = "image_url"
+)
+
+// imageExtensions lists the file extensions that are sent as ImageURL documents
+var imageExtensions = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".webp": true,
+	".gif":  true,
+}
+
+// DocumentTypeFor returns ImageURL if name has a common image extension and
+// DocumentURL otherwise. name may be a file path or a URL.
+func DocumentTypeFor(name string) string {
+	if i := strings.IndexAny(name, "?#"); i >= 0 && strings.Contains(name, "://") {
+		name = name[:i]
+	}
+
+	if imageExtensions[strings.ToLower(path.Ext(name))] {
+		return ImageURL
+	}
+	return DocumentURL
+}
+
+// Document identifies the document to run OCR on
+type Document struct {
+	Type        string `json:"type"`
+	DocumentURL string `json:"document_url,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+}
+
+// NewDocument returns a Document of the given type pointing at url
+func NewDocument(docType, url string) Document {
+	doc := Document{Type: docType}
+	if docType == ImageURL {
+		doc.ImageURL = url
+	} else {
+		doc.DocumentURL = url
+	}
+	return doc
+}
+
+// OCRRequest holds the parameters of a call to the OCR endpoint
+type OCRRequest struct {
+	// Model defaults to the client's model when empty
+	Model    string   `json:"model"`
+	Document Document `json:"document"`
+	// Pages restricts OCR to these zero-based page indices (all pages if empty)
+	Pages []int `json:"pages,omitempty"`
+	// IncludeImageBase64 asks the API to return extracted images inline
+	IncludeImageBase64 bool `json:"include_image_base64"`
+	// ImageLimit caps the number of images extracted (0 means no limit)
+	ImageLimit int `json:"image_limit,omitempty"`
+	// ImageMinSize skips images smaller than this many pixels per side
+	ImageMinSize int `json:"image_min_size,omitempty"`
+	// DocumentAnnotationFormat asks for structured data extracted from the
+	// whole document, returned in OCRResult.DocumentAnnotation
+	DocumentAnnotationFormat *ResponseFormat `json:"document_annotation_format,omitempty"`
+	// BBoxAnnotationFormat asks for structured data about every extracted
+	// image, returned in Image.ImageAnnotation
+	BBoxAnnotationFormat *ResponseFormat `json:"bbox_annotation_format,omitempty"`
+}
+
+// ResponseFormat describes the structure an annotation has to follow
+type ResponseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"`
+}
+
+// JSONSchema is a named JSON Schema used as a response format
+type JSONSchema struct {
+	// Name may only contain letters, digits, underscores and dashes
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Schema      json.RawMessage `json:"schema"`
+	Strict      bool            `json:"strict,omitempty"`
+}
+
+// NewJSONSchemaFormat returns a response format that follows schema
+func NewJSONSchemaFormat(name string, schema json.RawMessage, strict bool) *ResponseFormat {
+	return &ResponseFormat{
+		Type: "json_schema",
+		JSONSchema: &JSONSchema{
+			Name:   name,
+			Schema: schema,
+			Strict: strict,
+		},
+	}
+}
+
+// OCRResult is the decoded response of the OCR endpoint
+type OCRResult struct {
+	Pages     []Page    `json:"pages"`
+	Model     string    `json:"model,omitempty"`
+	UsageInfo UsageInfo `json:"usage_info,omitempty"`
+	// DocumentAnnotation is the JSON encoded annotation requested through
+	// OCRRequest.DocumentAnnotationFormat
+	DocumentAnnotation string `json:"document_annotation,omitempty"`
+	// Metadata is not returned by the API but may be added to saved results
+	// to control the title and header of converted documents
+	Metadata Metadata `json:"metadata,omitempty"`
+}
+
+// Page is the OCR output for a single page
+type Page struct {
+	// Index is the zero-based page number within the document
+	Index      int        `json:"index"`
+	Markdown   string     `json:"markdown"`
+	Images     []Image    `json:"images,omitempty"`
+	Dimensions Dimensions `json:"dimensions,omitempty"`
+}
+
+// Image is an image extracted from a page. Coordinates are in pixels
+// relative to the top-left corner of the page.
+type Image struct {
+	ID           string `json:"id"`
+	TopLeftX     int    `json:"top_left_x"`
+	TopLeftY     int    `json:"top_left_y"`
+	BottomRightX int    `json:"bottom_right_x"`
+	BottomRightY int    `json:"bottom_right_y"`
+	// ImageBase64 is only set when the request had IncludeImageBase64
+	ImageBase64 string `json:"image_base64,omitempty"`
+	// ImageAnnotation is the JSON encoded annotation requested through
+	// OCRRequest.BBoxAnnotationFormat
+	ImageAnnotation string `json:"image_annotation,omitempty"`
+}
+
+// Dimensions describes the rendered size of a page
+type Dimensions struct {
+	DPI    int `json:"dpi"`
+	Height int `json:"height"`
+	Width  int `json:"width"`
+}
+
+// UsageInfo reports what an OCR request was billed for
+type UsageInfo struct {
+	PagesProcessed int `json:"pages_processed"`
+	DocSizeBytes   int `json:"doc_size_bytes,omitempty"`
+}
+
+// Metadata holds optional document level information
+type Metadata struct {
+	Title        string `json:"title,omitempty"`
+	Author       string `json:"author,omitempty"`
+	CreationDate string `json:"creation_date,omitempty"`
+	PageCount    int    `json:"page_count,omitempty"`
+}
+
+// ParseOCRResult decodes a raw OCR response body
+func ParseOCRResult(data []byte) (*OCRResult, error) {
+	var result OCRResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("error parsing OCR response: %v", err)
+	}
+	return &result, nil
+}
+
+// MergeOCRResults combines the results of consecutive parts of one document.
+// offsets[i] is the index of the first page of parts[i] within the whole
+// document and is added to the page indices of that part. Document level
+// fields such as the document annotation are taken from the first part.
+func MergeOCRResults(parts []*OCRResult, offsets []int) *OCRResult {
+	merged := &OCRResult{}
+	for i, part := range parts {
+		if i == 0 {
+			merged.Model = part.Model
+			merged.Metadata = part.Metadata
+			merged.DocumentAnnotation = part.DocumentAnnotation
+		}
+
+		for _, page := range part.Pages {
+			if i < len(offsets) {
+				page.Index += offsets[i]
+			}
+			merged.Pages = append(merged.Pages, page)
+		}
+
+		merged.UsageInfo.PagesProcessed += part.UsageInfo.PagesProcessed
+		merged.UsageInfo.DocSizeBytes += part.UsageInfo.DocSizeBytes
+	}
+	return merged
+}
@@ -0,0 +1,89 @@
+package mistral
+
+import (
+	"net/http"
+	"time"
+)
+
+// DefaultTimeout is the per-request timeout used when none is configured
+const DefaultTimeout = 120 * time.Second
+
+// Option configures a Client created by NewClient
+type Option func(*clientConfig)
+
+type clientConfig struct {
+	baseURL    string
+	model      string
+	timeout    time.Duration
+	httpClient *http.Client
+	transport  http.RoundTripper
+	proxy      string
+	headers    map[string]string
+	userAgent  string
+	recorder   *Recorder
+	retry      *RetryPolicy
+
+	uploadLimit Limit
+	ocrLimit    Limit
+	otherLimit  Limit
+}
+
+// WithBaseURL sends requests to url instead of BaseURL, e.g. an API gateway
+func WithBaseURL(url string) Option {
+	return func(c *clientConfig) {
+		c.baseURL = url
+	}
+}
+
+// WithModel sets the OCR model used by requests that do not name one
+func WithModel(model string) Option {
+	return func(c *clientConfig) {
+		c.model = model
+	}
+}
+
+// WithTimeout sets the timeout for a single HTTP request
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *clientConfig) {
+		c.timeout = timeout
+	}
+}
+
+// WithHTTPClient makes the client send requests through hc. The timeout of
+// hc is kept unless WithTimeout is also given.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *clientConfig) {
+		c.httpClient = hc
+	}
+}
+
+// WithTransport sets the round tripper used for HTTP requests
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *clientConfig) {
+		c.transport = transport
+	}
+}
+
+// WithProxy routes requests through the proxy at url
+func WithProxy(url string) Option {
+	return func(c *clientConfig) {
+		c.proxy = url
+	}
+}
+
+// WithHeader adds a header that is sent with every request
+func WithHeader(key, value string) Option {
+	return func(c *clientConfig) {
+		if c.headers == nil {
+			c.headers = make(map[string]string)
+		}
+		c.headers[key] = value
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request
+func WithUserAgent(userAgent string) Option {
+	return func(c *clientConfig) {
+		c.userAgent = userAgent
+	}
+}
@@ -0,0 +1,160 @@
+package mistral
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Limit restricts the requests a client sends to a group of endpoints. The
+// zero value does not limit anything.
+type Limit struct {
+	// Rate is the number of requests started per second (unlimited if zero)
+	Rate float64
+	// Burst is the number of requests that may start at once when the rate
+	// allows it (1 if zero)
+	Burst int
+	// Concurrency caps the requests in flight (unlimited if zero)
+	Concurrency int
+}
+
+// WithUploadLimit limits file uploads to /files
+func WithUploadLimit(l Limit) Option {
+	return func(c *clientConfig) {
+		c.uploadLimit = l
+	}
+}
+
+// WithOCRLimit limits requests to /ocr
+func WithOCRLimit(l Limit) Option {
+	return func(c *clientConfig) {
+		c.ocrLimit = l
+	}
+}
+
+// WithRateLimit limits requests to all other endpoints, such as signed file
+// URLs, embeddings and chat
+func WithRateLimit(l Limit) Option {
+	return func(c *clientConfig) {
+		c.otherLimit = l
+	}
+}
+
+// limiter enforces a Limit with a token bucket and a semaphore. It is shared
+// by all goroutines using a client.
+type limiter struct {
+	rate  float64
+	burst float64
+	slots chan struct{}
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(l Limit) *limiter {
+	lim := &limiter{rate: l.Rate, burst: math.Max(float64(l.Burst), 1)}
+	lim.tokens = lim.burst
+	if l.Concurrency > 0 {
+		lim.slots = make(chan struct{}, l.Concurrency)
+	}
+	return lim
+}
+
+// acquire waits until a request may start. The returned function has to be
+// called once the request, including reading its response, is done.
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if l.slots != nil {
+			<-l.slots
+		}
+	}
+
+	if wait := l.reserve(); wait > 0 {
+		if err := sleep(ctx, wait); err != nil {
+			l.cancel()
+			release()
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+// reserve takes a token and returns how long to wait until it is available.
+// Tokens may go negative, which queues concurrent callers behind each other.
+func (l *limiter) reserve() time.Duration {
+	if l.rate <= 0 {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if !l.last.IsZero() {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// cancel returns the token of a reservation that was not used
+func (l *limiter) cancel() {
+	if l.rate <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = math.Min(l.burst, l.tokens+1)
+}
+
+// limits holds the limiters of the endpoint groups of a client
+type limits struct {
+	upload *limiter
+	ocr    *limiter
+	other  *limiter
+}
+
+func newLimits(cfg clientConfig) limits {
+	var l limits
+	if cfg.uploadLimit != (Limit{}) {
+		l.upload = newLimiter(cfg.uploadLimit)
+	}
+	if cfg.ocrLimit != (Limit{}) {
+		l.ocr = newLimiter(cfg.ocrLimit)
+	}
+	if cfg.otherLimit != (Limit{}) {
+		l.other = newLimiter(cfg.otherLimit)
+	}
+	return l
+}
+
+// forRequest returns the limiter of method requests to path
+func (l limits) forRequest(method, path string) *limiter {
+	switch {
+	case method == http.MethodPost && path == "/files":
+		return l.upload
+	case path == "/ocr":
+		return l.ocr
+	default:
+		return l.other
+	}
+}
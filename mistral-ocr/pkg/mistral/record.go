@@ -0,0 +1,253 @@
+package mistral
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// redactedHeaders are replaced in recordings since they carry credentials
+var redactedHeaders = []string{"Authorization", "X-Api-Key", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// Interaction is a recorded request and its response
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a request as sent by the client, with credentials redacted
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	// Body holds UTF-8 bodies, BodyBase64 any other
+	Body       string `json:"body,omitempty"`
+	BodyBase64 []byte `json:"body_base64,omitempty"`
+}
+
+// RecordedResponse is a response as received by the client
+type RecordedResponse struct {
+	Status     int         `json:"status"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body,omitempty"`
+	BodyBase64 []byte      `json:"body_base64,omitempty"`
+}
+
+func encodeBody(data []byte) (string, []byte) {
+	if utf8.Valid(data) {
+		return string(data), nil
+	}
+	return "", data
+}
+
+func decodeBody(text string, raw []byte) []byte {
+	if raw != nil {
+		return raw
+	}
+	return []byte(text)
+}
+
+func redact(header http.Header) http.Header {
+	header = header.Clone()
+	for _, name := range redactedHeaders {
+		if header.Get(name) != "" {
+			header.Set(name, "REDACTED")
+		}
+	}
+	return header
+}
+
+// requestKey identifies the requests a recording can answer
+func requestKey(method, rawURL string) string {
+	path := rawURL
+	if i := strings.Index(path, "://"); i >= 0 {
+		path = path[i+3:]
+		if j := strings.Index(path, "/"); j >= 0 {
+			path = path[j:]
+		} else {
+			path = "/"
+		}
+	}
+	return method + " " + path
+}
+
+// Recorder is an http.RoundTripper that saves every request and response to
+// a directory, one numbered JSON file per interaction. Credentials are redacted.
+type Recorder struct {
+	dir  string
+	next http.RoundTripper
+
+	mu sync.Mutex
+	n  int
+}
+
+// NewRecorder creates dir and returns a recorder writing to it. dir must not
+// contain recordings already.
+func NewRecorder(dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	existing, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		return nil, fmt.Errorf("%s already contains recordings", dir)
+	}
+	return &Recorder{dir: dir}, nil
+}
+
+// WithRecorder records all requests of the client with r
+func WithRecorder(r *Recorder) Option {
+	return func(c *clientConfig) {
+		c.recorder = r
+	}
+}
+
+var unsafeNameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	next := r.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	rec := Interaction{
+		Request:  RecordedRequest{Method: req.Method, URL: req.URL.String(), Header: redact(req.Header)},
+		Response: RecordedResponse{Status: resp.StatusCode, Header: redact(resp.Header)},
+	}
+	rec.Request.Body, rec.Request.BodyBase64 = encodeBody(reqBody)
+	rec.Response.Body, rec.Response.BodyBase64 = encodeBody(respBody)
+
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.n++
+	name := fmt.Sprintf("%04d-%s-%s.json", r.n, req.Method, strings.Trim(unsafeNameChars.ReplaceAllString(req.URL.Path, "-"), "-"))
+	if err := os.WriteFile(filepath.Join(r.dir, name), data, 0644); err != nil {
+		return nil, fmt.Errorf("error recording interaction: %v", err)
+	}
+	return resp, nil
+}
+
+// Replayer is an http.RoundTripper that answers requests with interactions
+// saved by a Recorder instead of sending them. Every recorded interaction is
+// used once, in order, for requests with the same method, path and query.
+// Requests without a recording get a 404 response.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewReplayer loads the recordings in dir
+func NewReplayer(dir string) (*Replayer, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no recordings found in %s", dir)
+	}
+	sort.Strings(files)
+
+	r := &Replayer{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var rec Interaction
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return nil, fmt.Errorf("%s: %v", filepath.Base(file), err)
+		}
+		r.interactions = append(r.interactions, rec)
+	}
+	r.used = make([]bool, len(r.interactions))
+	return r, nil
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		io.Copy(io.Discard, req.Body)
+		req.Body.Close()
+	}
+
+	key := requestKey(req.Method, req.URL.String())
+
+	r.mu.Lock()
+	var rec *Interaction
+	for i := range r.interactions {
+		if !r.used[i] && requestKey(r.interactions[i].Request.Method, r.interactions[i].Request.URL) == key {
+			r.used[i] = true
+			rec = &r.interactions[i]
+			break
+		}
+	}
+	r.mu.Unlock()
+
+	if rec == nil {
+		body, _ := json.Marshal(map[string]string{
+			"object":  "error",
+			"type":    "replay_miss",
+			"message": "no recorded response for " + key,
+		})
+		return &http.Response{
+			Status:     "404 Not Found",
+			StatusCode: http.StatusNotFound,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(bytes.NewReader(body)),
+			Request:    req,
+		}, nil
+	}
+
+	body := decodeBody(rec.Response.Body, rec.Response.BodyBase64)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.Response.Status, http.StatusText(rec.Response.Status)),
+		StatusCode:    rec.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        rec.Response.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
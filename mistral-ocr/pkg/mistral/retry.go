@@ -0,0 +1,212 @@
+package mistral
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// RetryPolicy decides whether and when failed requests are retried. It is
+// shared by all endpoints of a Client.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts; 1 disables retries
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. It doubles with every
+	// further retry up to MaxDelay.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts, including Retry-After
+	MaxDelay time.Duration
+	// Jitter randomizes every delay by up to this fraction, e.g. 0.2 for ±20%
+	Jitter float64
+	// Statuses lists the response statuses that are retried
+	// (DefaultRetryStatuses if nil)
+	Statuses map[int]bool
+}
+
+// DefaultRetryStatuses are retried by the default policy: timeouts, rate
+// limits and server errors
+var DefaultRetryStatuses = map[int]bool{
+	http.StatusRequestTimeout:      true,
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
+// DefaultRetryPolicy returns the policy used by clients created without WithRetryPolicy
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   2 * time.Second,
+		MaxDelay:    60 * time.Second,
+		Jitter:      0.2,
+	}
+}
+
+// WithRetryPolicy sets the retry policy of all requests
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *clientConfig) {
+		c.retry = &p
+	}
+}
+
+// RetryStatus reports whether responses with status are retried
+func (p RetryPolicy) RetryStatus(status int) bool {
+	if p.Statuses == nil {
+		return DefaultRetryStatuses[status]
+	}
+	return p.Statuses[status]
+}
+
+// Delay returns the wait before retry number n (starting at 1). A positive
+// retryAfter requested by the server replaces the backoff.
+func (p RetryPolicy) Delay(n int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		if p.MaxDelay > 0 && retryAfter > p.MaxDelay {
+			return p.MaxDelay
+		}
+		return retryAfter
+	}
+
+	// Doubling stops at MaxDelay, or before it would overflow without one
+	delay := p.BaseDelay
+	for i := 1; i < n && (p.MaxDelay <= 0 || delay < p.MaxDelay) && delay <= math.MaxInt64/2; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		jittered := float64(delay) * (1 + p.Jitter*(2*rand.Float64()-1))
+		if jittered >= math.MaxInt64 {
+			return math.MaxInt64
+		}
+		delay = time.Duration(jittered)
+	}
+	return delay
+}
+
+// retryError marks an error as worth another attempt
+type retryError struct {
+	err error
+	// after is the delay requested by the server with Retry-After
+	after time.Duration
+}
+
+func (e *retryError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryError) Unwrap() error {
+	return e.err
+}
+
+// retry calls attempt until it succeeds, returns an error that is not a
+// retryError or the policy gives up
+func (c *Client) retry(ctx context.Context, attempt func() error) error {
+	p := c.retryPolicy
+	attempts := p.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var lastErr error
+	for n := 1; n <= attempts; n++ {
+		if n > 1 {
+			var after time.Duration
+			var re *retryError
+			if errors.As(lastErr, &re) {
+				after = re.after
+			}
+			if err := sleep(ctx, p.Delay(n-1, after)); err != nil {
+				return err
+			}
+		}
+
+		err := attempt()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var re *retryError
+		if !errors.As(err, &re) {
+			return err
+		}
+		lastErr = err
+	}
+
+	if attempts == 1 {
+		return errors.Unwrap(lastErr)
+	}
+	return fmt.Errorf("failed after %d attempts. Last error: %w", attempts, errors.Unwrap(lastErr))
+}
+
+// sendError classifies an error returned while sending a request. Network
+// failures are retried; errors raised before the request could be sent, such
+// as an invalid URL or an unreadable file, are not.
+func sendError(err error, format string) error {
+	wrapped := fmt.Errorf(format, err)
+
+	cause := err
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		cause = urlErr.Err
+	}
+
+	var netErr net.Error
+	if errors.As(cause, &netErr) ||
+		errors.Is(cause, io.EOF) ||
+		errors.Is(cause, io.ErrUnexpectedEOF) ||
+		errors.Is(cause, syscall.ECONNRESET) ||
+		errors.Is(cause, syscall.ECONNREFUSED) {
+		return &retryError{err: wrapped}
+	}
+	return wrapped
+}
+
+// statusError returns the *APIError for a response that is not 200 OK,
+// marked for a retry if the policy retries its status
+func (c *Client) statusError(resp *resty.Response) error {
+	return c.apiError(resp.StatusCode(), resp.Header(), resp.Body())
+}
+
+func (c *Client) apiError(status int, header http.Header, body []byte) error {
+	err := newAPIError(status, header, body)
+	err.Retryable = c.retryPolicy.RetryStatus(status)
+	if !err.Retryable {
+		return err
+	}
+	return &retryError{err: err, after: retryAfter(header)}
+}
+
+// retryAfter parses a Retry-After header given in seconds or as HTTP date
+func retryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
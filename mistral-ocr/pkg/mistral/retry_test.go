@@ -0,0 +1,190 @@
+package mistral
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	tests := []struct {
+		name       string
+		n          int
+		retryAfter time.Duration
+		want       time.Duration
+	}{
+		{name: "first retry", n: 1, want: time.Second},
+		{name: "doubles", n: 2, want: 2 * time.Second},
+		{name: "doubles again", n: 4, want: 8 * time.Second},
+		{name: "capped", n: 5, want: 10 * time.Second},
+		{name: "stays capped", n: 50, want: 10 * time.Second},
+		{name: "retry after", n: 3, retryAfter: 3 * time.Second, want: 3 * time.Second},
+		{name: "retry after capped", n: 1, retryAfter: time.Minute, want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.Delay(tt.n, tt.retryAfter); got != tt.want {
+				t.Errorf("Delay(%d, %v) = %v, want %v", tt.n, tt.retryAfter, got, tt.want)
+			}
+		})
+	}
+
+	uncapped := RetryPolicy{BaseDelay: time.Second}
+	if got, want := uncapped.Delay(6, 0), 32*time.Second; got != want {
+		t.Errorf("Delay without MaxDelay = %v, want %v", got, want)
+	}
+	// Without MaxDelay the backoff saturates instead of overflowing
+	for _, n := range []int{40, 70, 1000} {
+		if got := uncapped.Delay(n, 0); got < uncapped.Delay(30, 0) {
+			t.Errorf("Delay(%d) without MaxDelay = %v, want a very long delay", n, got)
+		}
+	}
+	jittered := RetryPolicy{BaseDelay: time.Second, Jitter: 0.2}
+	if got := jittered.Delay(70, 0); got <= 0 {
+		t.Errorf("Delay(70) with jitter = %v, want a positive delay", got)
+	}
+}
+
+func TestRetryPolicyJitter(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: time.Minute, Jitter: 0.2}
+
+	for n := 1; n <= 4; n++ {
+		base := time.Second << (n - 1)
+		low, high := time.Duration(float64(base)*0.8), time.Duration(float64(base)*1.2)
+		varied := false
+		for i := 0; i < 200; i++ {
+			d := p.Delay(n, 0)
+			if d < low || d > high {
+				t.Fatalf("Delay(%d) = %v, want between %v and %v", n, d, low, high)
+			}
+			varied = varied || d != base
+		}
+		if !varied {
+			t.Errorf("Delay(%d) is never randomized", n)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		// min and max bound the result, which depends on the clock for dates
+		min, max time.Duration
+	}{
+		{name: "none", value: ""},
+		{name: "seconds", value: "7", min: 7 * time.Second, max: 7 * time.Second},
+		{name: "zero", value: "0"},
+		{name: "negative", value: "-3"},
+		{name: "invalid", value: "soon"},
+		{name: "date", value: time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat), min: 28 * time.Second, max: 30 * time.Second},
+		{name: "past date", value: time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.value != "" {
+				header.Set("Retry-After", tt.value)
+			}
+			if got := retryAfter(header); got < tt.min || got > tt.max {
+				t.Errorf("retryAfter(%q) = %v, want between %v and %v", tt.value, got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	errRetry := &retryError{err: errors.New("try again")}
+	errFatal := errors.New("fatal")
+
+	tests := []struct {
+		name     string
+		attempts int
+		// results are returned by consecutive attempts, the last one repeatedly
+		results   []error
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "success", attempts: 3, results: []error{nil}, wantCalls: 1},
+		{name: "success after retries", attempts: 3, results: []error{errRetry, errRetry, nil}, wantCalls: 3},
+		{name: "not retried", attempts: 3, results: []error{errFatal}, wantCalls: 1, wantErr: errFatal},
+		{name: "exhausted", attempts: 3, results: []error{errRetry}, wantCalls: 3, wantErr: errRetry.err},
+		{name: "single attempt", attempts: 1, results: []error{errRetry}, wantCalls: 1, wantErr: errRetry.err},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{retryPolicy: RetryPolicy{MaxAttempts: tt.attempts, BaseDelay: time.Millisecond}}
+
+			calls := 0
+			err := c.retry(context.Background(), func() error {
+				err := tt.results[min(calls, len(tt.results)-1)]
+				calls++
+				return err
+			})
+			if calls != tt.wantCalls {
+				t.Errorf("made %d attempts, want %d", calls, tt.wantCalls)
+			}
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			var re *retryError
+			if errors.As(err, &re) {
+				t.Errorf("error %v still carries the retry marker", err)
+			}
+		})
+	}
+}
+
+func TestRetryStopsOnCancel(t *testing.T) {
+	c := &Client{retryPolicy: RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour}}
+
+	t.Run("while waiting", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		calls := 0
+		start := time.Now()
+		err := c.retry(ctx, func() error {
+			calls++
+			return &retryError{err: errors.New("try again")}
+		})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("error = %v, want the context error", err)
+		}
+		if calls != 1 {
+			t.Errorf("made %d attempts, want 1", calls)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("retry returned after %v, not when the context was done", elapsed)
+		}
+	})
+
+	t.Run("during an attempt", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		err := c.retry(ctx, func() error {
+			calls++
+			cancel()
+			return &retryError{err: errors.New("request cancelled")}
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("error = %v, want the context error", err)
+		}
+		if calls != 1 {
+			t.Errorf("made %d attempts, want 1", calls)
+		}
+	})
+}
@@ -0,0 +1,309 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// Object is any PDF value: nil, bool, int64, float64, String, Name, Array,
+// Dict, Ref or *Stream
+type Object interface{}
+
+// Name is a PDF name such as /Type, stored without the leading slash
+type Name string
+
+// String is the decoded content of a literal or hexadecimal PDF string
+type String []byte
+
+// Array is a PDF array
+type Array []Object
+
+// Dict is a PDF dictionary
+type Dict map[Name]Object
+
+// Ref is an indirect reference to object Num with generation Gen
+type Ref struct {
+	Num int
+	Gen int
+}
+
+// Stream is a stream object. Data holds the raw, still encoded bytes.
+type Stream struct {
+	Dict Dict
+	Data []byte
+}
+
+// parser reads PDF objects from a byte slice
+type parser struct {
+	buf []byte
+	pos int
+}
+
+func isWhitespace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func isRegular(c byte) bool {
+	return !isWhitespace(c) && !isDelimiter(c)
+}
+
+// skipSpace advances past whitespace and comments
+func (p *parser) skipSpace() {
+	for p.pos < len(p.buf) {
+		c := p.buf[p.pos]
+		if isWhitespace(c) {
+			p.pos++
+			continue
+		}
+		if c == '%' {
+			for p.pos < len(p.buf) && p.buf[p.pos] != '\n' && p.buf[p.pos] != '\r' {
+				p.pos++
+			}
+			continue
+		}
+		return
+	}
+}
+
+// keyword reads a run of regular characters without consuming it
+func (p *parser) peekKeyword() string {
+	p.skipSpace()
+	end := p.pos
+	for end < len(p.buf) && isRegular(p.buf[end]) {
+		end++
+	}
+	return string(p.buf[p.pos:end])
+}
+
+// readKeyword reads and consumes a run of regular characters
+func (p *parser) readKeyword() string {
+	kw := p.peekKeyword()
+	p.pos += len(kw)
+	return kw
+}
+
+// readInt reads an unsigned integer token
+func (p *parser) readInt() (int, error) {
+	kw := p.readKeyword()
+	n, err := strconv.Atoi(kw)
+	if err != nil {
+		return 0, fmt.Errorf("expected integer at offset %d, got %q", p.pos, kw)
+	}
+	return n, nil
+}
+
+// parseObject reads the next direct object. Indirect references are
+// returned as Ref; streams are handled by the reader.
+func (p *parser) parseObject() (Object, error) {
+	p.skipSpace()
+	if p.pos >= len(p.buf) {
+		return nil, fmt.Errorf("unexpected end of data")
+	}
+
+	switch c := p.buf[p.pos]; {
+	case c == '/':
+		return p.parseName(), nil
+	case c == '(':
+		return p.parseLiteralString()
+	case c == '<':
+		if p.pos+1 < len(p.buf) && p.buf[p.pos+1] == '<' {
+			return p.parseDict()
+		}
+		return p.parseHexString()
+	case c == '[':
+		return p.parseArray()
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return p.parseNumberOrRef()
+	}
+
+	switch kw := p.readKeyword(); kw {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unexpected token %q at offset %d", kw, p.pos)
+	}
+}
+
+func (p *parser) parseName() Name {
+	p.pos++ // skip '/'
+	var name []byte
+	for p.pos < len(p.buf) && isRegular(p.buf[p.pos]) {
+		c := p.buf[p.pos]
+		if c == '#' && p.pos+2 < len(p.buf) {
+			if v, err := strconv.ParseUint(string(p.buf[p.pos+1:p.pos+3]), 16, 8); err == nil {
+				name = append(name, byte(v))
+				p.pos += 3
+				continue
+			}
+		}
+		name = append(name, c)
+		p.pos++
+	}
+	return Name(name)
+}
+
+func (p *parser) parseLiteralString() (Object, error) {
+	p.pos++ // skip '('
+	var s []byte
+	depth := 1
+	for p.pos < len(p.buf) {
+		c := p.buf[p.pos]
+		p.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return String(s), nil
+			}
+		case '\\':
+			if p.pos >= len(p.buf) {
+				break
+			}
+			e := p.buf[p.pos]
+			p.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				// Line continuation, optionally followed by \n
+				if p.pos < len(p.buf) && p.buf[p.pos] == '\n' {
+					p.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && p.pos < len(p.buf) && p.buf[p.pos] >= '0' && p.buf[p.pos] <= '7'; i++ {
+						v = v*8 + int(p.buf[p.pos]-'0')
+						p.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		s = append(s, c)
+	}
+	return nil, fmt.Errorf("unterminated string")
+}
+
+func (p *parser) parseHexString() (Object, error) {
+	p.pos++ // skip '<'
+	var digits []byte
+	for p.pos < len(p.buf) && p.buf[p.pos] != '>' {
+		if !isWhitespace(p.buf[p.pos]) {
+			digits = append(digits, p.buf[p.pos])
+		}
+		p.pos++
+	}
+	if p.pos >= len(p.buf) {
+		return nil, fmt.Errorf("unterminated hex string")
+	}
+	p.pos++ // skip '>'
+
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	s := make([]byte, len(digits)/2)
+	for i := range s {
+		v, err := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex string")
+		}
+		s[i] = byte(v)
+	}
+	return String(s), nil
+}
+
+func (p *parser) parseArray() (Object, error) {
+	p.pos++ // skip '['
+	arr := Array{}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.buf) {
+			return nil, fmt.Errorf("unterminated array")
+		}
+		if p.buf[p.pos] == ']' {
+			p.pos++
+			return arr, nil
+		}
+		obj, err := p.parseObject()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, obj)
+	}
+}
+
+func (p *parser) parseDict() (Object, error) {
+	p.pos += 2 // skip '<<'
+	dict := Dict{}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.buf) {
+			return nil, fmt.Errorf("unterminated dictionary")
+		}
+		if bytes.HasPrefix(p.buf[p.pos:], []byte(">>")) {
+			p.pos += 2
+			return dict, nil
+		}
+		if p.buf[p.pos] != '/' {
+			return nil, fmt.Errorf("expected name key at offset %d", p.pos)
+		}
+		key := p.parseName()
+		value, err := p.parseObject()
+		if err != nil {
+			return nil, err
+		}
+		dict[key] = value
+	}
+}
+
+func (p *parser) parseNumberOrRef() (Object, error) {
+	kw := p.readKeyword()
+
+	if n, err := strconv.ParseInt(kw, 10, 64); err == nil {
+		// An integer may be the start of an "n g R" reference
+		if n >= 0 {
+			save := p.pos
+			gen := p.readKeyword()
+			if g, err := strconv.Atoi(gen); err == nil && g >= 0 && p.readKeyword() == "R" {
+				return Ref{Num: int(n), Gen: g}, nil
+			}
+			p.pos = save
+		}
+		return n, nil
+	}
+
+	f, err := strconv.ParseFloat(kw, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q at offset %d", kw, p.pos)
+	}
+	return f, nil
+}
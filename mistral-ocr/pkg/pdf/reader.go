@@ -0,0 +1,657 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// xrefEntry locates an object either at a byte offset or inside an object stream
+type xrefEntry struct {
+	offset    int64
+	inStream  bool
+	streamNum int
+	index     int
+}
+
+// Reader gives access to the objects and pages of a PDF file held in memory
+type Reader struct {
+	buf     []byte
+	xref    map[int]xrefEntry
+	trailer Dict
+	cache   map[int]Object
+	// resolving guards against reference cycles while loading objects
+	resolving map[int]bool
+	pages     []page
+	// treeNodes holds the intermediate nodes of the page tree
+	treeNodes map[Ref]bool
+}
+
+// page is a leaf of the page tree with inherited attributes resolved
+type page struct {
+	ref  Ref
+	dict Dict
+}
+
+// Open reads and parses the PDF file at path
+func Open(path string) (*Reader, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(data)
+}
+
+// NewReader parses a PDF document from data
+func NewReader(data []byte) (*Reader, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\n\f\r "), []byte("%PDF-")) {
+		return nil, fmt.Errorf("not a PDF file")
+	}
+
+	r := &Reader{
+		buf:       data,
+		xref:      make(map[int]xrefEntry),
+		cache:     make(map[int]Object),
+		resolving: make(map[int]bool),
+		treeNodes: make(map[Ref]bool),
+	}
+
+	if err := r.readXref(); err != nil {
+		// Damaged or unusual cross-reference data: rebuild it by scanning the file
+		if err := r.rebuildXref(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := r.trailer["Encrypt"]; ok {
+		return nil, fmt.Errorf("encrypted PDFs are not supported")
+	}
+
+	if err := r.loadPages(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// NumPages returns the number of pages in the document
+func (r *Reader) NumPages() int {
+	return len(r.pages)
+}
+
+// readXref loads the cross-reference sections starting at startxref
+func (r *Reader) readXref() error {
+	tail := r.buf
+	if len(tail) > 4096 {
+		tail = tail[len(tail)-4096:]
+	}
+	i := bytes.LastIndex(tail, []byte("startxref"))
+	if i < 0 {
+		return fmt.Errorf("startxref not found")
+	}
+	p := &parser{buf: tail, pos: i + len("startxref")}
+	offset, err := p.readInt()
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[int]bool)
+	for offset > 0 {
+		if seen[offset] || offset >= len(r.buf) {
+			return fmt.Errorf("invalid xref offset %d", offset)
+		}
+		seen[offset] = true
+
+		trailer, err := r.readXrefSection(offset)
+		if err != nil {
+			return err
+		}
+		if r.trailer == nil {
+			r.trailer = trailer
+		}
+
+		// Hybrid files keep additional entries in a cross-reference stream
+		if stm, ok := trailer["XRefStm"].(int64); ok {
+			if _, err := r.readXrefSection(int(stm)); err != nil {
+				return err
+			}
+		}
+
+		prev, ok := trailer["Prev"].(int64)
+		if !ok {
+			break
+		}
+		offset = int(prev)
+	}
+
+	if _, ok := r.trailer["Root"].(Ref); !ok {
+		return fmt.Errorf("trailer has no /Root")
+	}
+	return nil
+}
+
+// readXrefSection reads a classic xref table or an xref stream at offset and
+// returns its trailer dictionary. Entries already known from newer sections win.
+func (r *Reader) readXrefSection(offset int) (Dict, error) {
+	p := &parser{buf: r.buf, pos: offset}
+	if p.peekKeyword() != "xref" {
+		return r.readXrefStream(offset)
+	}
+	p.readKeyword()
+
+	for {
+		if p.peekKeyword() == "trailer" {
+			p.readKeyword()
+			obj, err := p.parseObject()
+			if err != nil {
+				return nil, err
+			}
+			trailer, ok := obj.(Dict)
+			if !ok {
+				return nil, fmt.Errorf("invalid trailer")
+			}
+			return trailer, nil
+		}
+
+		start, err := p.readInt()
+		if err != nil {
+			return nil, err
+		}
+		count, err := p.readInt()
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < count; i++ {
+			off, err := p.readInt()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.readInt(); err != nil {
+				return nil, err
+			}
+			kind := p.readKeyword()
+			num := start + i
+			if _, known := r.xref[num]; known {
+				continue
+			}
+			if kind == "n" {
+				r.xref[num] = xrefEntry{offset: int64(off)}
+			} else {
+				// Record free entries so older sections cannot resurrect them
+				r.xref[num] = xrefEntry{offset: -1}
+			}
+		}
+	}
+}
+
+// readXrefStream reads a cross-reference stream object at offset
+func (r *Reader) readXrefStream(offset int) (Dict, error) {
+	_, obj, err := r.parseIndirectAt(int64(offset))
+	if err != nil {
+		return nil, err
+	}
+	stream, ok := obj.(*Stream)
+	if !ok || stream.Dict["Type"] != Name("XRef") {
+		return nil, fmt.Errorf("no xref at offset %d", offset)
+	}
+
+	data, err := r.decodeStream(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	w, ok := stream.Dict["W"].(Array)
+	if !ok || len(w) != 3 {
+		return nil, fmt.Errorf("invalid /W in xref stream")
+	}
+	var widths [3]int
+	for i, v := range w {
+		n, _ := v.(int64)
+		widths[i] = int(n)
+	}
+	entrySize := widths[0] + widths[1] + widths[2]
+	if entrySize == 0 {
+		return nil, fmt.Errorf("invalid /W in xref stream")
+	}
+
+	index := Array{int64(0), stream.Dict["Size"]}
+	if idx, ok := stream.Dict["Index"].(Array); ok {
+		index = idx
+	}
+
+	field := func(b []byte) int64 {
+		var v int64
+		for _, c := range b {
+			v = v<<8 | int64(c)
+		}
+		return v
+	}
+
+	pos := 0
+	for i := 0; i+1 < len(index); i += 2 {
+		start, _ := index[i].(int64)
+		count, _ := index[i+1].(int64)
+		for j := int64(0); j < count; j++ {
+			if pos+entrySize > len(data) {
+				return stream.Dict, nil
+			}
+			entry := data[pos : pos+entrySize]
+			pos += entrySize
+
+			kind := int64(1)
+			if widths[0] > 0 {
+				kind = field(entry[:widths[0]])
+			}
+			a := field(entry[widths[0] : widths[0]+widths[1]])
+			b := field(entry[widths[0]+widths[1]:])
+
+			num := int(start + j)
+			if _, known := r.xref[num]; known {
+				continue
+			}
+			switch kind {
+			case 0:
+				r.xref[num] = xrefEntry{offset: -1}
+			case 1:
+				r.xref[num] = xrefEntry{offset: a}
+			case 2:
+				r.xref[num] = xrefEntry{inStream: true, streamNum: int(a), index: int(b)}
+			}
+		}
+	}
+	return stream.Dict, nil
+}
+
+var objectHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// rebuildXref recovers object offsets and the trailer by scanning the whole file
+func (r *Reader) rebuildXref() error {
+	r.xref = make(map[int]xrefEntry)
+	r.cache = make(map[int]Object)
+	r.trailer = nil
+
+	for _, m := range objectHeader.FindAllSubmatchIndex(r.buf, -1) {
+		// Object headers must start a line or follow whitespace
+		if m[0] > 0 && !isWhitespace(r.buf[m[0]-1]) {
+			continue
+		}
+		num, _ := strconv.Atoi(string(r.buf[m[2]:m[3]]))
+		r.xref[num] = xrefEntry{offset: int64(m[0])}
+	}
+
+	// Register the objects stored inside object streams
+	var streams []int
+	for num := range r.xref {
+		if s, ok := r.Resolve(Ref{Num: num}).(*Stream); ok && s.Dict["Type"] == Name("ObjStm") {
+			streams = append(streams, num)
+		}
+	}
+	for _, num := range streams {
+		s := r.cache[num].(*Stream)
+		data, err := r.decodeStream(s)
+		if err != nil {
+			continue
+		}
+		n, _ := s.Dict["N"].(int64)
+		p := &parser{buf: data}
+		for i := 0; i < int(n); i++ {
+			objNum, err := p.readInt()
+			if err != nil {
+				break
+			}
+			if _, err := p.readInt(); err != nil {
+				break
+			}
+			if _, known := r.xref[objNum]; !known {
+				r.xref[objNum] = xrefEntry{inStream: true, streamNum: num, index: i}
+			}
+		}
+	}
+
+	if i := bytes.LastIndex(r.buf, []byte("trailer")); i >= 0 {
+		p := &parser{buf: r.buf, pos: i + len("trailer")}
+		if obj, err := p.parseObject(); err == nil {
+			r.trailer, _ = obj.(Dict)
+		}
+	}
+	if _, ok := r.trailer["Root"].(Ref); ok {
+		return nil
+	}
+
+	// Without a usable trailer, look for the document catalog directly
+	for num := range r.xref {
+		dict, ok := r.Resolve(Ref{Num: num}).(Dict)
+		if ok && dict["Type"] == Name("Catalog") {
+			if r.trailer == nil {
+				r.trailer = Dict{}
+			}
+			r.trailer["Root"] = Ref{Num: num}
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot find document catalog")
+}
+
+// parseIndirectAt parses the "n g obj ... endobj" object at offset
+func (r *Reader) parseIndirectAt(offset int64) (int, Object, error) {
+	p := &parser{buf: r.buf, pos: int(offset)}
+	num, err := p.readInt()
+	if err != nil {
+		return 0, nil, err
+	}
+	if _, err := p.readInt(); err != nil {
+		return 0, nil, err
+	}
+	if kw := p.readKeyword(); kw != "obj" {
+		return 0, nil, fmt.Errorf("expected obj at offset %d", offset)
+	}
+
+	obj, err := p.parseObject()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	dict, ok := obj.(Dict)
+	if !ok || p.peekKeyword() != "stream" {
+		return num, obj, nil
+	}
+	p.readKeyword()
+
+	// The stream data starts after the end-of-line following the keyword
+	if p.pos < len(r.buf) && r.buf[p.pos] == '\r' {
+		p.pos++
+	}
+	if p.pos < len(r.buf) && r.buf[p.pos] == '\n' {
+		p.pos++
+	}
+	start := p.pos
+
+	length := -1
+	switch l := dict["Length"].(type) {
+	case int64:
+		length = int(l)
+	case Ref:
+		if n, ok := r.Resolve(l).(int64); ok {
+			length = int(n)
+		}
+	}
+
+	end := start + length
+	if length < 0 || end > len(r.buf) || !bytes.HasPrefix(bytes.TrimLeft(r.buf[end:], "\x00\t\n\f\r "), []byte("endstream")) {
+		// Missing or wrong /Length: fall back to searching for endstream
+		i := bytes.Index(r.buf[start:], []byte("endstream"))
+		if i < 0 {
+			return 0, nil, fmt.Errorf("unterminated stream in object %d", num)
+		}
+		end = start + i
+		for end > start && (r.buf[end-1] == '\n' || r.buf[end-1] == '\r') {
+			end--
+		}
+	}
+
+	return num, &Stream{Dict: dict, Data: r.buf[start:end]}, nil
+}
+
+// Resolve follows obj if it is a reference and returns the referenced object.
+// Missing or unreadable objects resolve to nil.
+func (r *Reader) Resolve(obj Object) Object {
+	ref, ok := obj.(Ref)
+	if !ok {
+		return obj
+	}
+	if cached, ok := r.cache[ref.Num]; ok {
+		return cached
+	}
+	if r.resolving[ref.Num] {
+		return nil
+	}
+	r.resolving[ref.Num] = true
+	defer delete(r.resolving, ref.Num)
+
+	entry, ok := r.xref[ref.Num]
+	if !ok || (!entry.inStream && entry.offset < 0) {
+		return nil
+	}
+
+	var resolved Object
+	if entry.inStream {
+		resolved = r.loadFromObjectStream(entry)
+	} else if _, o, err := r.parseIndirectAt(entry.offset); err == nil {
+		resolved = o
+	}
+
+	r.cache[ref.Num] = resolved
+	return resolved
+}
+
+// loadFromObjectStream reads a compressed object out of its object stream
+func (r *Reader) loadFromObjectStream(entry xrefEntry) Object {
+	stream, ok := r.Resolve(Ref{Num: entry.streamNum}).(*Stream)
+	if !ok {
+		return nil
+	}
+	data, err := r.decodeStream(stream)
+	if err != nil {
+		return nil
+	}
+
+	n, _ := stream.Dict["N"].(int64)
+	first, _ := stream.Dict["First"].(int64)
+	if entry.index >= int(n) {
+		return nil
+	}
+
+	p := &parser{buf: data}
+	var offset int
+	for i := 0; i <= entry.index; i++ {
+		if _, err := p.readInt(); err != nil {
+			return nil
+		}
+		if offset, err = p.readInt(); err != nil {
+			return nil
+		}
+	}
+
+	p.pos = int(first) + offset
+	obj, err := p.parseObject()
+	if err != nil {
+		return nil
+	}
+	return obj
+}
+
+// decodeStream applies the stream filters needed to read xref and object streams
+func (r *Reader) decodeStream(s *Stream) ([]byte, error) {
+	filters := Array{}
+	switch f := r.Resolve(s.Dict["Filter"]).(type) {
+	case Name:
+		filters = Array{f}
+	case Array:
+		filters = f
+	}
+	params := Array{}
+	switch dp := r.Resolve(s.Dict["DecodeParms"]).(type) {
+	case Dict:
+		params = Array{dp}
+	case Array:
+		params = dp
+	}
+
+	data := s.Data
+	for i, f := range filters {
+		if f != Name("FlateDecode") {
+			return nil, fmt.Errorf("unsupported stream filter %v", f)
+		}
+		zr, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		decoded, err := io.ReadAll(zr)
+		if err != nil && len(decoded) == 0 {
+			return nil, err
+		}
+		data = decoded
+
+		if i < len(params) {
+			if dp, ok := r.Resolve(params[i]).(Dict); ok {
+				if data, err = applyPredictor(data, dp); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return data, nil
+}
+
+// applyPredictor reverses the PNG predictors used by xref streams
+func applyPredictor(data []byte, params Dict) ([]byte, error) {
+	predictor, _ := params["Predictor"].(int64)
+	if predictor < 10 {
+		return data, nil
+	}
+
+	columns := int64(1)
+	if c, ok := params["Columns"].(int64); ok {
+		columns = c
+	}
+	colors := int64(1)
+	if c, ok := params["Colors"].(int64); ok {
+		colors = c
+	}
+	bpc := int64(8)
+	if b, ok := params["BitsPerComponent"].(int64); ok {
+		bpc = b
+	}
+	bpp := int((colors*bpc + 7) / 8)
+	rowLen := int((columns*colors*bpc + 7) / 8)
+
+	var out []byte
+	prev := make([]byte, rowLen)
+	for pos := 0; pos+rowLen+1 <= len(data); pos += rowLen + 1 {
+		filter := data[pos]
+		row := make([]byte, rowLen)
+		copy(row, data[pos+1:pos+1+rowLen])
+
+		for i := 0; i < rowLen; i++ {
+			var left, upLeft byte
+			if i >= bpp {
+				left = row[i-bpp]
+				upLeft = prev[i-bpp]
+			}
+			up := prev[i]
+			switch filter {
+			case 0:
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			default:
+				return nil, fmt.Errorf("unsupported PNG predictor %d", filter)
+			}
+		}
+		out = append(out, row...)
+		prev = row
+	}
+	return out, nil
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	if pa <= pb && pa <= pc {
+		return a
+	}
+	if pb <= pc {
+		return b
+	}
+	return c
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// inheritableAttributes are page attributes that may be set on an ancestor
+// node of the page tree instead of the page itself
+var inheritableAttributes = []Name{"Resources", "MediaBox", "CropBox", "Rotate"}
+
+// loadPages walks the page tree and records every page with inherited
+// attributes copied into its own dictionary
+func (r *Reader) loadPages() error {
+	root, ok := r.Resolve(r.trailer["Root"]).(Dict)
+	if !ok {
+		return fmt.Errorf("cannot read document catalog")
+	}
+
+	visited := make(map[Ref]bool)
+	var walk func(node Object, inherited Dict) error
+	walk = func(node Object, inherited Dict) error {
+		ref, isRef := node.(Ref)
+		if isRef {
+			if visited[ref] {
+				return fmt.Errorf("cycle in page tree")
+			}
+			visited[ref] = true
+		}
+
+		dict, ok := r.Resolve(node).(Dict)
+		if !ok {
+			return nil
+		}
+
+		attrs := Dict{}
+		for k, v := range inherited {
+			attrs[k] = v
+		}
+		for _, k := range inheritableAttributes {
+			if v, ok := dict[k]; ok {
+				attrs[k] = v
+			}
+		}
+
+		kids, isTree := r.Resolve(dict["Kids"]).(Array)
+		if dict["Type"] == Name("Pages") || (dict["Type"] == nil && isTree) {
+			if isRef {
+				r.treeNodes[ref] = true
+			}
+			for _, kid := range kids {
+				if err := walk(kid, attrs); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		pageDict := Dict{}
+		for k, v := range dict {
+			pageDict[k] = v
+		}
+		for k, v := range attrs {
+			pageDict[k] = v
+		}
+		// Pages that are not indirect objects get a reference no real object uses
+		if !isRef {
+			ref = Ref{Num: -len(r.pages) - 1}
+		}
+		r.pages = append(r.pages, page{ref: ref, dict: pageDict})
+		return nil
+	}
+
+	if err := walk(root["Pages"], nil); err != nil {
+		return err
+	}
+	if len(r.pages) == 0 {
+		return fmt.Errorf("document has no pages")
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package pdf
+
+import "fmt"
+
+// Chunk is a standalone PDF holding a consecutive range of pages of a larger document
+type Chunk struct {
+	// FirstPage is the zero-based index of the chunk's first page in the source document
+	FirstPage int
+	PageCount int
+	Data      []byte
+}
+
+// Split divides the document into consecutive page ranges whose encoded size
+// does not exceed maxSize. It fails if a single page is larger than maxSize.
+func (r *Reader) Split(maxSize int64) ([]Chunk, error) {
+	total := len(r.pages)
+
+	// Start from the average page size with some headroom for shared
+	// resources such as fonts, which are copied into every chunk
+	perChunk := int(int64(total) * maxSize / int64(len(r.buf)) * 9 / 10)
+	if perChunk < 1 {
+		perChunk = 1
+	}
+
+	var chunks []Chunk
+	for first := 0; first < total; {
+		count := perChunk
+		if count > total-first {
+			count = total - first
+		}
+
+		for {
+			data, err := r.ExtractPages(first, count)
+			if err != nil {
+				return nil, err
+			}
+			if int64(len(data)) <= maxSize {
+				chunks = append(chunks, Chunk{FirstPage: first, PageCount: count, Data: data})
+				break
+			}
+			if count == 1 {
+				return nil, fmt.Errorf("page %d alone is larger than %.2f MB", first+1, float64(maxSize)/1024/1024)
+			}
+
+			// Shrink proportionally to the overshoot
+			smaller := int(int64(count) * maxSize / int64(len(data)) * 9 / 10)
+			if smaller >= count {
+				smaller = count - 1
+			}
+			if smaller < 1 {
+				smaller = 1
+			}
+			count = smaller
+		}
+
+		first += count
+		perChunk = count
+	}
+
+	return chunks, nil
+}
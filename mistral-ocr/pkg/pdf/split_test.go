@@ -0,0 +1,149 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF returns a document with n pages whose Resources and MediaBox are
+// set on the page tree root only, so pages have to inherit them. Every page
+// has a content stream of contentSize bytes.
+func buildPDF(n, contentSize int) []byte {
+	var objects []string
+	kids := make([]string, n)
+	for i := 0; i < n; i++ {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /Resources << /Font << /F1 3 0 R >> >> /MediaBox [0 0 612 792] >>",
+			strings.Join(kids, " "), n),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	)
+	for i := 0; i < n; i++ {
+		content := fmt.Sprintf("BT /F1 12 Tf (Page %d) Tj ET\n", i+1)
+		content += strings.Repeat("%", contentSize-len(content))
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /Contents %d 0 R >>", 5+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name    string
+		pages   int
+		maxSize int64
+		// chunks is the expected number of chunks, 0 to only check the invariants
+		chunks int
+	}{
+		{name: "fits in one chunk", pages: 5, maxSize: 1 << 20, chunks: 1},
+		{name: "one page per chunk", pages: 4, maxSize: 2000, chunks: 4},
+		{name: "several pages per chunk", pages: 20, maxSize: 5000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := NewReader(buildPDF(tt.pages, 1000))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			chunks, err := doc.Split(tt.maxSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.chunks > 0 && len(chunks) != tt.chunks {
+				t.Errorf("got %d chunks, want %d", len(chunks), tt.chunks)
+			}
+
+			next := 0
+			for i, chunk := range chunks {
+				if chunk.FirstPage != next {
+					t.Errorf("chunk %d starts at page %d, want %d", i, chunk.FirstPage, next)
+				}
+				next = chunk.FirstPage + chunk.PageCount
+
+				if int64(len(chunk.Data)) > tt.maxSize {
+					t.Errorf("chunk %d has %d bytes, more than %d", i, len(chunk.Data), tt.maxSize)
+				}
+
+				part, err := NewReader(chunk.Data)
+				if err != nil {
+					t.Fatalf("chunk %d does not parse: %v", i, err)
+				}
+				if part.NumPages() != chunk.PageCount {
+					t.Errorf("chunk %d has %d pages, want %d", i, part.NumPages(), chunk.PageCount)
+				}
+				for j, p := range part.pages {
+					if _, ok := part.Resolve(p.dict["MediaBox"]).(Array); !ok {
+						t.Errorf("chunk %d page %d lost the inherited MediaBox", i, j)
+					}
+					resources, ok := part.Resolve(p.dict["Resources"]).(Dict)
+					if !ok {
+						t.Fatalf("chunk %d page %d lost the inherited Resources", i, j)
+					}
+					fonts, _ := part.Resolve(resources["Font"]).(Dict)
+					if font, _ := part.Resolve(fonts["F1"]).(Dict); font["BaseFont"] != Name("Helvetica") {
+						t.Errorf("chunk %d page %d lost its font, got %v", i, j, font)
+					}
+				}
+			}
+			if next != tt.pages {
+				t.Errorf("chunks cover %d pages, want %d", next, tt.pages)
+			}
+		})
+	}
+}
+
+func TestSplitPageTooLarge(t *testing.T) {
+	doc, err := NewReader(buildPDF(2, 5000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := doc.Split(1000); err == nil {
+		t.Fatal("expected an error for a page larger than the maximum size")
+	}
+}
+
+func TestExtractPagesKeepsContent(t *testing.T) {
+	doc, err := NewReader(buildPDF(3, 200))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := doc.ExtractPages(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte("(Page 2) Tj")) {
+		t.Error("extracted page does not contain its own content")
+	}
+	if bytes.Contains(data, []byte("(Page 1) Tj")) || bytes.Contains(data, []byte("(Page 3) Tj")) {
+		t.Error("extracted page contains content of other pages")
+	}
+
+	if _, err := doc.ExtractPages(2, 2); err == nil {
+		t.Error("expected an error for a range past the last page")
+	}
+}
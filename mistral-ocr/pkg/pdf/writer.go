@@ -0,0 +1,228 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Object numbers of the catalog and page tree root in extracted documents
+const (
+	catalogNum = 1
+	pagesNum   = 2
+)
+
+// extractor copies the objects reachable from a set of pages into a new document
+type extractor struct {
+	r *Reader
+	// numbers maps object numbers of the source document to the new document
+	numbers map[int]int
+	// excluded holds source objects that must not be copied, such as pages
+	// outside the range and the page tree, which would pull in everything
+	excluded map[int]bool
+	queue    []int
+	objects  map[int]Object
+	next     int
+}
+
+// ExtractPages returns a standalone PDF document holding count pages
+// starting at the zero-based page index first
+func (r *Reader) ExtractPages(first, count int) ([]byte, error) {
+	if first < 0 || count < 1 || first+count > len(r.pages) {
+		return nil, fmt.Errorf("page range %d-%d out of bounds (document has %d pages)", first+1, first+count, len(r.pages))
+	}
+
+	e := &extractor{
+		r:        r,
+		numbers:  make(map[int]int),
+		excluded: make(map[int]bool),
+		objects:  make(map[int]Object),
+		next:     pagesNum + 1,
+	}
+
+	if root, ok := r.trailer["Root"].(Ref); ok {
+		e.excluded[root.Num] = true
+	}
+	for ref := range r.treeNodes {
+		e.excluded[ref.Num] = true
+	}
+	for i, p := range r.pages {
+		if i < first || i >= first+count {
+			e.excluded[p.ref.Num] = true
+		}
+	}
+
+	// Allocate the pages first so references between them resolve to the copies
+	selected := r.pages[first : first+count]
+	kids := make(Array, len(selected))
+	pageNums := make([]int, len(selected))
+	for i, p := range selected {
+		pageNums[i] = e.next
+		e.numbers[p.ref.Num] = e.next
+		kids[i] = Ref{Num: e.next}
+		e.next++
+	}
+
+	for i, p := range selected {
+		dict := Dict{}
+		for k, v := range p.dict {
+			if k != "Parent" {
+				dict[k] = v
+			}
+		}
+		copied := e.copy(dict).(Dict)
+		copied["Parent"] = Ref{Num: pagesNum}
+		e.objects[pageNums[i]] = copied
+	}
+
+	for len(e.queue) > 0 {
+		num := e.queue[0]
+		e.queue = e.queue[1:]
+		e.objects[e.numbers[num]] = e.copy(r.Resolve(Ref{Num: num}))
+	}
+
+	e.objects[catalogNum] = Dict{"Type": Name("Catalog"), "Pages": Ref{Num: pagesNum}}
+	e.objects[pagesNum] = Dict{"Type": Name("Pages"), "Kids": kids, "Count": int64(len(kids))}
+
+	return e.write(), nil
+}
+
+// copy returns obj with every reference renumbered for the new document.
+// References to excluded objects are dropped.
+func (e *extractor) copy(obj Object) Object {
+	switch v := obj.(type) {
+	case Ref:
+		if e.excluded[v.Num] {
+			return nil
+		}
+		if num, ok := e.numbers[v.Num]; ok {
+			return Ref{Num: num}
+		}
+		if e.r.Resolve(v) == nil {
+			return nil
+		}
+		e.numbers[v.Num] = e.next
+		e.next++
+		e.queue = append(e.queue, v.Num)
+		return Ref{Num: e.numbers[v.Num]}
+	case Dict:
+		dict := Dict{}
+		for k, item := range v {
+			if c := e.copy(item); c != nil {
+				dict[k] = c
+			}
+		}
+		return dict
+	case Array:
+		arr := make(Array, len(v))
+		for i, item := range v {
+			arr[i] = e.copy(item)
+		}
+		return arr
+	case *Stream:
+		// The length is written directly so an indirect /Length is not copied
+		dict := Dict{}
+		for k, item := range v.Dict {
+			if k != "Length" {
+				dict[k] = item
+			}
+		}
+		dict = e.copy(dict).(Dict)
+		dict["Length"] = int64(len(v.Data))
+		return &Stream{Dict: dict, Data: v.Data}
+	default:
+		return obj
+	}
+}
+
+// write serializes the collected objects as a PDF file
+func (e *extractor) write() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+
+	offsets := make([]int, e.next)
+	for num := 1; num < e.next; num++ {
+		offsets[num] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n", num)
+
+		if s, ok := e.objects[num].(*Stream); ok {
+			writeObject(&buf, s.Dict)
+			buf.WriteString("\nstream\n")
+			buf.Write(s.Data)
+			buf.WriteString("\nendstream")
+		} else {
+			writeObject(&buf, e.objects[num])
+		}
+		buf.WriteString("\nendobj\n")
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", e.next)
+	for num := 1; num < e.next; num++ {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offsets[num])
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", e.next, catalogNum, xref)
+
+	return buf.Bytes()
+}
+
+// writeObject serializes a direct object
+func writeObject(buf *bytes.Buffer, obj Object) {
+	switch v := obj.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case int64:
+		buf.WriteString(strconv.FormatInt(v, 10))
+	case float64:
+		buf.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+	case String:
+		fmt.Fprintf(buf, "<%x>", []byte(v))
+	case Name:
+		writeName(buf, v)
+	case Ref:
+		fmt.Fprintf(buf, "%d %d R", v.Num, v.Gen)
+	case Array:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(' ')
+			}
+			writeObject(buf, item)
+		}
+		buf.WriteByte(']')
+	case Dict:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, string(k))
+		}
+		sort.Strings(keys)
+
+		buf.WriteString("<<")
+		for _, k := range keys {
+			buf.WriteByte(' ')
+			writeName(buf, Name(k))
+			buf.WriteByte(' ')
+			writeObject(buf, v[Name(k)])
+		}
+		buf.WriteString(" >>")
+	case *Stream:
+		// Streams are only valid as indirect objects; callers write them separately
+		buf.WriteString("null")
+	}
+}
+
+// writeName serializes a name, escaping characters that are not allowed literally
+func writeName(buf *bytes.Buffer, name Name) {
+	buf.WriteByte('/')
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c < '!' || c > '~' || c == '#' || isDelimiter(c) {
+			fmt.Fprintf(buf, "#%02X", c)
+			continue
+		}
+		buf.WriteByte(c)
+	}
+}
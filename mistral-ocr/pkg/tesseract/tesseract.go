@@ -0,0 +1,179 @@
+// Package tesseract runs OCR locally with the tesseract command line tool.
+// PDF pages are rasterized with pdftoppm from poppler-utils first. Results
+// have the shape of Mistral OCR responses so they can be converted the same way.
+package tesseract
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/mistral"
+	"github.com/setkyar/llm-tools/mistral-ocr/pkg/pdf"
+)
+
+// DefaultDPI is the resolution PDF pages are rasterized at
+const DefaultDPI = 300
+
+// Engine runs tesseract on documents
+type Engine struct {
+	// Lang is passed to tesseract -l, e.g. "eng" or "eng+deu" (tesseract's default if empty)
+	Lang string
+	// DPI is the resolution PDF pages are rasterized at (DefaultDPI if zero)
+	DPI int
+	// Tesseract and Pdftoppm are the commands to run (looked up in PATH if empty)
+	Tesseract string
+	Pdftoppm  string
+}
+
+// Model identifies the engine and the settings that change its output, e.g.
+// in cache keys: "tesseract:eng@300dpi", or "tesseract@300dpi" without a language
+func (e *Engine) Model() string {
+	model := "tesseract"
+	if e.Lang != "" {
+		model += ":" + e.Lang
+	}
+	return fmt.Sprintf("%s@%ddpi", model, e.dpi())
+}
+
+func (e *Engine) dpi() int {
+	if e.DPI > 0 {
+		return e.DPI
+	}
+	return DefaultDPI
+}
+
+func command(name, fallback string) string {
+	if name != "" {
+		return name
+	}
+	return fallback
+}
+
+// OCR recognizes the text of the image or PDF at path. pages restricts PDFs
+// to these zero-based page indices (all pages if empty); an index past the
+// end of the document is an error.
+func (e *Engine) OCR(ctx context.Context, path string, pages []int) (*mistral.OCRResult, error) {
+	result := &mistral.OCRResult{Model: e.Model()}
+
+	if mistral.DocumentTypeFor(path) == mistral.ImageURL {
+		page, err := e.ocrImage(ctx, path, 0)
+		if err != nil {
+			return nil, err
+		}
+		result.Pages = []mistral.Page{page}
+		result.UsageInfo.PagesProcessed = 1
+		return result, nil
+	}
+
+	doc, err := pdf.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading PDF: %v", err)
+	}
+	if len(pages) == 0 {
+		for i := 0; i < doc.NumPages(); i++ {
+			pages = append(pages, i)
+		}
+	}
+	for _, index := range pages {
+		if index < 0 || index >= doc.NumPages() {
+			return nil, fmt.Errorf("page %d is out of range, the document has %d pages", index+1, doc.NumPages())
+		}
+	}
+
+	tmpDir, err := os.MkdirTemp("", "mistral-ocr-tesseract-*")
+	if err != nil {
+		return nil, fmt.Errorf("error creating temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for _, index := range pages {
+		prefix := filepath.Join(tmpDir, fmt.Sprintf("page-%d", index+1))
+		cmd := exec.CommandContext(ctx, command(e.Pdftoppm, "pdftoppm"),
+			"-r", fmt.Sprint(e.dpi()), "-f", fmt.Sprint(index+1), "-l", fmt.Sprint(index+1),
+			"-png", "-singlefile", path, prefix)
+		if err := run(cmd); err != nil {
+			return nil, fmt.Errorf("error rasterizing page %d: %v", index+1, err)
+		}
+
+		page, err := e.ocrImage(ctx, prefix+".png", index)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %v", index+1, err)
+		}
+		page.Dimensions.DPI = e.dpi()
+		result.Pages = append(result.Pages, page)
+		os.Remove(prefix + ".png")
+	}
+	result.UsageInfo.PagesProcessed = len(result.Pages)
+	return result, nil
+}
+
+// ocrImage runs tesseract on a single image
+func (e *Engine) ocrImage(ctx context.Context, path string, index int) (mistral.Page, error) {
+	page := mistral.Page{Index: index}
+	if f, err := os.Open(path); err == nil {
+		if config, _, err := image.DecodeConfig(f); err == nil {
+			page.Dimensions = mistral.Dimensions{Width: config.Width, Height: config.Height}
+		}
+		f.Close()
+	}
+
+	args := []string{path, "stdout"}
+	if e.Lang != "" {
+		args = append(args, "-l", e.Lang)
+	}
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, command(e.Tesseract, "tesseract"), args...)
+	cmd.Stdout = &out
+	if err := run(cmd); err != nil {
+		return page, fmt.Errorf("tesseract failed: %v", err)
+	}
+
+	page.Markdown = textToMarkdown(out.String())
+	return page, nil
+}
+
+// run runs cmd, reporting its stderr output on failure
+func run(cmd *exec.Cmd) error {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%v: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+var blankLines = regexp.MustCompile(`\n\s*\n`)
+
+// textToMarkdown turns tesseract's plain text into Markdown paragraphs.
+// Characters that Markdown would interpret at the start of a line are escaped.
+func textToMarkdown(text string) string {
+	text = strings.ReplaceAll(text, "\f", "")
+	var paragraphs []string
+	for _, para := range blankLines.Split(strings.TrimSpace(text), -1) {
+		lines := strings.Split(para, "\n")
+		for i, line := range lines {
+			line = strings.TrimRight(line, " \t")
+			if strings.HasPrefix(line, "#") || strings.HasPrefix(line, ">") {
+				line = `\` + line
+			}
+			lines[i] = line
+		}
+		if para := strings.Join(lines, "\n"); para != "" {
+			paragraphs = append(paragraphs, para)
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
@@ -0,0 +1,67 @@
+package tesseract
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestModel(t *testing.T) {
+	tests := []struct {
+		engine Engine
+		want   string
+	}{
+		{engine: Engine{}, want: "tesseract@300dpi"},
+		{engine: Engine{DPI: 150}, want: "tesseract@150dpi"},
+		{engine: Engine{Lang: "eng+deu", DPI: 200}, want: "tesseract:eng+deu@200dpi"},
+	}
+
+	// The model is part of cache keys, so settings that change the output have to change it
+	for _, tt := range tests {
+		if got := tt.engine.Model(); got != tt.want {
+			t.Errorf("Model() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+// onePagePDF is a minimal document with a single empty page
+const onePagePDF = `%PDF-1.4
+1 0 obj
+<< /Type /Catalog /Pages 2 0 R >>
+endobj
+2 0 obj
+<< /Type /Pages /Kids [3 0 R] /Count 1 >>
+endobj
+3 0 obj
+<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >>
+endobj
+`
+
+func TestOCRPageOutOfRange(t *testing.T) {
+	// The xref table needs the byte offset of every object
+	var buf strings.Builder
+	buf.WriteString(onePagePDF)
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 4\n0000000000 65535 f \n")
+	for _, obj := range []string{"1 0 obj", "2 0 obj", "3 0 obj"} {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", strings.Index(onePagePDF, obj))
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size 4 /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", xref)
+
+	path := filepath.Join(t.TempDir(), "doc.pdf")
+	if err := os.WriteFile(path, []byte(buf.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The pages are checked before any page is rasterized
+	e := &Engine{Pdftoppm: "pdftoppm-not-installed", Tesseract: "tesseract-not-installed"}
+	for _, pages := range [][]int{{3}, {0, 1}, {-1}} {
+		_, err := e.OCR(context.Background(), path, pages)
+		if err == nil || !strings.Contains(err.Error(), "out of range, the document has 1 pages") {
+			t.Errorf("OCR(pages %v) error = %v, want the page reported out of range", pages, err)
+		}
+	}
+}